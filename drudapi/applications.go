@@ -69,38 +69,159 @@ type Deploy struct {
 	AutoManaged   bool   `json:"auto_managed,omitempty"`
 	MigrateFrom   string `json:"migrate_from,omitempty"`
 	Url           string `json:"url,omitempty"`
+	// Hostnames are custom hostnames pointed at the deploy, see AddHostname
+	Hostnames []CustomHostname `json:"hostnames,omitempty"`
+	// BasicAuthSecret is the vault path of generated credentials, see RotateBasicAuth
+	BasicAuthSecret  string `json:"basicauth_secret,omitempty"`
+	BasicAuthRotated string `json:"basicauth_rotated,omitempty"`
+	// Extra holds fields returned by the api that Deploy does not know about
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the Deploy and keeps any fields it does not know about in Extra
+func (d *Deploy) UnmarshalJSON(data []byte) error {
+	type plain Deploy
+	return unmarshalWithExtra(data, (*plain)(d), &d.Extra)
+}
+
+// MarshalJSON encodes the Deploy along with the fields kept in Extra
+func (d Deploy) MarshalJSON() ([]byte, error) {
+	type plain Deploy
+	return marshalWithExtra(plain(d), d.Extra)
+}
+
+// SiteURL returns the url the deploy is served at built from its Protocol and
+// Url, falling back to its Hostname
+func (d Deploy) SiteURL() string {
+	host := d.Url
+	if host == "" {
+		host = d.Hostname
+	}
+	if host == "" {
+		return ""
+	}
+
+	protocol := d.Protocol
+	if protocol == "" {
+		protocol = "http"
+	}
+	return protocol + "://" + host
+}
+
+// GetHostname returns the custom hostname with the given name
+func (d *Deploy) GetHostname(name string) *CustomHostname {
+	for i := range d.Hostnames {
+		if sameHost(d.Hostnames[i].Name, name) {
+			return &d.Hostnames[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks the deploy's name is dns safe and its protocol and hostname are valid
+func (d Deploy) Validate(method string) error {
+	v := &validation{}
+	if d.Name == "" {
+		v.add("name", "is required")
+	} else if !IsDNSLabel(d.Name) {
+		v.add("name", "%q must be lowercase letters, numbers and hyphens", d.Name)
+	}
+	if d.Protocol != "" && d.Protocol != "http" && d.Protocol != "https" {
+		v.add("protocol", "%q must be http or https", d.Protocol)
+	}
+	if d.Hostname != "" && !IsHostname(d.Hostname) {
+		v.add("hostname", "%q is not a valid hostname", d.Hostname)
+	}
+	for i, h := range d.Hostnames {
+		if !IsHostname(h.Name) {
+			v.add(fmt.Sprintf("hostnames[%d].name", i), "%q is not a valid hostname", h.Name)
+		}
+	}
+	return v.err()
+}
+
+// RepoDetails describes where an application's repository is hosted
+type RepoDetails struct {
+	Host     string `json:"host,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Org      string `json:"org,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	Dest     string `json:"dest,omitempty"`
+	CloneURL string `json:"clone_url,omitempty"`
 }
 
 // Application ...
 type Application struct {
-	AppID          string   `json:"app_id,omitempty"`
-	Client         Client   `json:"client,omitempty"`
-	Deploys        []Deploy `json:"deploys,omitempty"`
-	GithubHookID   int      `json:"github_hook_id,omitempty"`
-	RepoOrg        string   `json:"repo_org,omitempty"`
-	Name           string   `json:"name,omitempty"`
-	Repo           string   `json:"repo,omitempty"`
-	SlackChannel   string   `json:"slack_channel,omitempty"`
-	AuthKey        string   `json:"auth_key,omitempty"`
-	SecureAuthKey  string   `json:"secure_auth_key,omitempty"`
-	LoggedInKey    string   `json:"logged_in_key,omitempty"`
-	NonceKey       string   `json:"nonce_key,omitempty"`
-	AuthSalt       string   `json:"auth_salt,omitempty"`
-	SecureAuthSalt string   `json:"secure_auth_salt,omitempty"`
-	LoggedInSalt   string   `json:"logged_in_salt,omitempty"`
-	NonceSalt      string   `json:"nonce_salt,omitempty"`
-	RepoDetails    *struct {
-		Host     string `json:"host,omitempty"`
-		Name     string `json:"name,omitempty"`
-		Org      string `json:"org,omitempty"`
-		Branch   string `json:"branch,omitempty"`
-		Dest     string `json:"dest,omitempty"`
-		CloneURL string `json:"clone_url,omitempty"`
-	} `json:"repo_details,omitempty"`
-	Created string `json:"_created,omitempty"`
-	Etag    string `json:"_etag,omitempty"`
-	ID      string `json:"_id,omitempty"`
-	Updated string `json:"_updated,omitempty"`
+	AppID          string       `json:"app_id,omitempty"`
+	Client         Client       `json:"client,omitempty"`
+	Deploys        []Deploy     `json:"deploys,omitempty"`
+	GithubHookID   int          `json:"github_hook_id,omitempty"`
+	RepoOrg        string       `json:"repo_org,omitempty"`
+	Name           string       `json:"name,omitempty"`
+	Repo           string       `json:"repo,omitempty"`
+	SlackChannel   string       `json:"slack_channel,omitempty"`
+	AuthKey        string       `json:"auth_key,omitempty"`
+	SecureAuthKey  string       `json:"secure_auth_key,omitempty"`
+	LoggedInKey    string       `json:"logged_in_key,omitempty"`
+	NonceKey       string       `json:"nonce_key,omitempty"`
+	AuthSalt       string       `json:"auth_salt,omitempty"`
+	SecureAuthSalt string       `json:"secure_auth_salt,omitempty"`
+	LoggedInSalt   string       `json:"logged_in_salt,omitempty"`
+	NonceSalt      string       `json:"nonce_salt,omitempty"`
+	RepoDetails    *RepoDetails `json:"repo_details,omitempty"`
+	Created        string       `json:"_created,omitempty"`
+	Etag           string       `json:"_etag,omitempty"`
+	ID             string       `json:"_id,omitempty"`
+	Updated        string       `json:"_updated,omitempty"`
+	// Extra holds fields returned by the api that Application does not know about
+	Extra    map[string]json.RawMessage `json:"-"`
+	relation relation
+}
+
+// UnmarshalJSON decodes the Application and keeps any fields it does not know about in Extra.
+// A bare _id is decoded as a reference.
+func (a *Application) UnmarshalJSON(data []byte) error {
+	ref, err := decodeReference(data, &a.ID)
+	if ref || err != nil {
+		a.relation = relationReference
+		return err
+	}
+
+	type plain Application
+	err = unmarshalWithExtra(data, (*plain)(a), &a.Extra)
+	if err != nil {
+		return err
+	}
+
+	a.relation = relationEmbedded
+	return nil
+}
+
+// MarshalJSON encodes the Application along with the fields kept in Extra. References are
+// encoded as their _id.
+func (a Application) MarshalJSON() ([]byte, error) {
+	if a.relation == relationReference {
+		return json.Marshal(a.ID)
+	}
+
+	type plain Application
+	return marshalWithExtra(plain(a), a.Extra)
+}
+
+// ApplicationRef returns a reference to the application with the given _id, which is
+// sent as the bare _id
+func ApplicationRef(id string) Application {
+	return Application{ID: id, relation: relationReference}
+}
+
+// IsReference returns true when the api returned only the application's _id
+func (a Application) IsReference() bool {
+	return a.relation == relationReference
+}
+
+// IsEmbedded returns true when the api returned the full application
+func (a Application) IsEmbedded() bool {
+	return a.relation == relationEmbedded
 }
 
 // Path ...
@@ -111,6 +232,10 @@ func (a Application) Path(method string) string {
 		path = "application"
 	} else {
 		path = "application/" + a.AppID
+		// references only know the _id which eve also accepts as the item lookup
+		if a.AppID == "" {
+			path = "application/" + a.ID
+		}
 	}
 	return path
 }
@@ -121,11 +246,48 @@ func (a *Application) Unmarshal(data []byte) error {
 	return err
 }
 
-// GetDeploy looks for a deploy by name and returns it
+// Validate checks the application's required fields and each of its deploys
+func (a Application) Validate(method string) error {
+	v := &validation{}
+	if method == "POST" {
+		v.required("name", a.Name)
+		// a referenced client only has its _id, which is all eve needs
+		if a.Client.IsReference() {
+			v.required("client._id", a.Client.ID)
+		} else {
+			v.required("client.name", a.Client.Name)
+		}
+	}
+
+	names := make(map[string]bool)
+	for i, d := range a.Deploys {
+		field := fmt.Sprintf("deploys[%d]", i)
+		v.merge(field, d.Validate(method))
+		if d.Name != "" && names[d.Name] {
+			v.add(field+".name", "%q is used by another deploy", d.Name)
+		}
+		names[d.Name] = true
+	}
+	return v.err()
+}
+
+// ClientName returns the name of the application's client. Eve only returns the client's
+// _id unless it is embedded, in which case the name is looked up in names, see
+// ClientNamesByID.
+func (a Application) ClientName(names map[string]string) string {
+	if a.Client.Name != "" {
+		return a.Client.Name
+	}
+	return names[a.Client.ID]
+}
+
+// GetDeploy looks for a deploy by name and returns a pointer to it within a.Deploys, or
+// nil if there is none. Changes made through the pointer modify the application, e.g.
+// before a Patch, and it should not be kept after deploys are added or removed.
 func (a *Application) GetDeploy(name string) *Deploy {
-	for _, d := range a.Deploys {
-		if d.Name == name {
-			return &d
+	for i := range a.Deploys {
+		if a.Deploys[i].Name == name {
+			return &a.Deploys[i]
 		}
 	}
 	return nil
@@ -223,7 +385,11 @@ func (a *Application) Describe() {
 		deployTable.AddRow("TEMPLATE:", dep.Template)
 		deployTable.AddRow("BRANCH:", dep.Branch)
 		deployTable.AddRow("AUTH USER:", dep.BasicAuthUser)
-		deployTable.AddRow("AUTH PASS:", dep.BasicAuthPass)
+		if dep.BasicAuthSecret != "" {
+			deployTable.AddRow("AUTH SECRET:", dep.BasicAuthSecret)
+		} else if dep.BasicAuthPass != "" {
+			deployTable.AddRow("AUTH PASS:", "********")
+		}
 		deployTable.AddRow("AUTO MANAGED:", managed)
 		deployTable.AddRow("\n")
 	}
@@ -285,3 +451,18 @@ func (a *ApplicationList) Describe() {
 	fmt.Println(table)
 
 }
+
+// AllApplications gets every page of applications
+func (r *Request) AllApplications() ([]Application, error) {
+	var apps []Application
+	err := r.eachPage(ApplicationList{}.Path("GET"), "", func(data []byte) error {
+		list := &ApplicationList{}
+		err := list.Unmarshal(data)
+		apps = append(apps, list.Items...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
@@ -0,0 +1,113 @@
+package drudapi
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	vaultAPI "github.com/hashicorp/vault/api"
+)
+
+// BasicAuthPasswordLength is the length of generated basic auth passwords
+var BasicAuthPasswordLength = 32
+
+const passwordChars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// SecretWriter stores secrets, *vaultAPI.Logical is a SecretWriter
+type SecretWriter interface {
+	Write(path string, data map[string]interface{}) (*vaultAPI.Secret, error)
+}
+
+// BasicAuth is a set of generated basic auth credentials and where they are stored
+type BasicAuth struct {
+	User       string
+	Pass       string
+	SecretPath string
+}
+
+// BasicAuthSecretPath is the vault path a deploy's basic auth credentials are stored at
+func BasicAuthSecretPath(appID string, deployName string) string {
+	return fmt.Sprintf("secret/%s/%s/basicauth", appID, deployName)
+}
+
+// GeneratePassword returns a random alphanumeric password of the given length
+func GeneratePassword(length int) (string, error) {
+	max := big.NewInt(int64(len(passwordChars)))
+	pass := make([]byte, length)
+	for i := range pass {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		pass[i] = passwordChars[n.Int64()]
+	}
+	return string(pass), nil
+}
+
+// BasicAuthCredentials returns the deploy's basic auth user and password, reading them
+// with lookup if the deploy refers to a secret
+func (d Deploy) BasicAuthCredentials(lookup SecretLookup) (string, string, error) {
+	if d.BasicAuthSecret == "" {
+		return d.BasicAuthUser, d.BasicAuthPass, nil
+	}
+	if lookup == nil {
+		return "", "", fmt.Errorf("Secret lookup required to read basic auth for %s", d.Name)
+	}
+
+	user, err := lookup(d.BasicAuthSecret, "username")
+	if err != nil {
+		return "", "", err
+	}
+	pass, err := lookup(d.BasicAuthSecret, "password")
+	if err != nil {
+		return "", "", err
+	}
+	return user, pass, nil
+}
+
+// RotateBasicAuth generates new basic auth credentials for a deploy and writes them to
+// vault at BasicAuthSecretPath. The deploy is then patched to refer to the secret, with
+// any password it carried removed, and the rotation time recorded. The deploy's user name
+// is kept if it has one. The new credentials are returned so they can be shown once.
+func (r *Request) RotateBasicAuth(vault SecretWriter, app *Application, deployName string) (*BasicAuth, error) {
+	d := app.GetDeploy(deployName)
+	if d == nil {
+		return nil, fmt.Errorf("No deploy found by name %s", deployName)
+	}
+
+	pass, err := GeneratePassword(BasicAuthPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+
+	auth := &BasicAuth{
+		User:       d.BasicAuthUser,
+		Pass:       pass,
+		SecretPath: BasicAuthSecretPath(app.AppID, deployName),
+	}
+	if auth.User == "" {
+		auth.User = deployName
+	}
+
+	_, err = vault.Write(auth.SecretPath, map[string]interface{}{
+		"username": auth.User,
+		"password": auth.Pass,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Could not store basic auth for %s in vault: %s", deployName, err)
+	}
+
+	previous := *d
+	d.BasicAuthUser = auth.User
+	d.BasicAuthPass = ""
+	d.BasicAuthSecret = auth.SecretPath
+	d.BasicAuthRotated = time.Now().UTC().Format(time.RFC1123)
+
+	err = r.Patch(app)
+	if err != nil {
+		*d = previous
+		return nil, fmt.Errorf("Basic auth for %s was rotated in vault but not saved to the api: %s", deployName, err)
+	}
+	return auth, nil
+}
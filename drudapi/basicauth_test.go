@@ -0,0 +1,111 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	vaultAPI "github.com/hashicorp/vault/api"
+)
+
+// fakeVault stores secrets in memory
+type fakeVault map[string]map[string]interface{}
+
+func (v fakeVault) Write(path string, data map[string]interface{}) (*vaultAPI.Secret, error) {
+	if path == "secret/sealed/production/basicauth" {
+		return nil, errors.New("vault is sealed")
+	}
+	v[path] = data
+	return nil, nil
+}
+
+func (v fakeVault) lookup(path string, key string) (string, error) {
+	secret, ok := v[path]
+	if !ok {
+		return "", fmt.Errorf("No secret at %s", path)
+	}
+	return fmt.Sprint(secret[key]), nil
+}
+
+func TestRotateBasicAuth(t *testing.T) {
+	var patched Application
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		patched = Application{}
+		json.Unmarshal(body, &patched)
+		fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+	vault := fakeVault{}
+	app := &Application{
+		AppID:   "myapp",
+		Etag:    "etag1",
+		Deploys: []Deploy{{Name: "production", BasicAuthUser: "admin", BasicAuthPass: "hunter2"}},
+	}
+
+	auth, err := r.RotateBasicAuth(vault, app, "production")
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, auth.User, "admin")
+	expect(t, len(auth.Pass), BasicAuthPasswordLength)
+	expect(t, auth.SecretPath, "secret/myapp/production/basicauth")
+	expect(t, vault[auth.SecretPath]["password"], auth.Pass)
+
+	// the api only gets the reference to the secret
+	d := patched.Deploys[0]
+	expect(t, d.BasicAuthPass, "")
+	expect(t, d.BasicAuthSecret, auth.SecretPath)
+	refute(t, d.BasicAuthRotated, "")
+
+	user, pass, err := app.Deploys[0].BasicAuthCredentials(vault.lookup)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, user, "admin")
+	expect(t, pass, auth.Pass)
+
+	rotated, err := r.RotateBasicAuth(vault, app, "production")
+	if err != nil {
+		log.Fatal(err)
+	}
+	refute(t, rotated.Pass, auth.Pass)
+	expect(t, vault[auth.SecretPath]["password"], rotated.Pass)
+
+	_, _, err = app.Deploys[0].BasicAuthCredentials(nil)
+	refute(t, err, nil)
+}
+
+func TestRotateBasicAuthVaultFailure(t *testing.T) {
+	r := Request{
+		Host: "http://127.0.0.1:1",
+	}
+	app := &Application{AppID: "sealed", Deploys: []Deploy{{Name: "production", BasicAuthPass: "hunter2"}}}
+
+	_, err := r.RotateBasicAuth(fakeVault{}, app, "production")
+	refute(t, err, nil)
+	expect(t, app.Deploys[0].BasicAuthPass, "hunter2")
+	expect(t, app.Deploys[0].BasicAuthSecret, "")
+}
+
+func TestGeneratePassword(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		pass, err := GeneratePassword(24)
+		if err != nil {
+			log.Fatal(err)
+		}
+		expect(t, len(pass), 24)
+		expect(t, seen[pass], false)
+		seen[pass] = true
+	}
+}
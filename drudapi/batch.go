@@ -0,0 +1,254 @@
+package drudapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BatchWorkers is the number of concurrent requests used when a batch call is given
+// a worker limit less than 1
+var BatchWorkers = 4
+
+// BatchResult is the outcome of one item of a batch call
+type BatchResult struct {
+	Index  int          // position of the entity in the slice passed to the batch call
+	Entity EntityGetter // the entity, updated from the api response on success
+	Err    error
+}
+
+// batch runs fn on count items with at most workers running concurrently
+func (r *Request) batch(count int, workers int, fn func(q *Request, i int) error) []BatchResult {
+	results := make([]BatchResult, count)
+	if count == 0 {
+		return results
+	}
+
+	if workers < 1 {
+		workers = BatchWorkers
+	}
+	if workers > count {
+		workers = count
+	}
+
+	// load credentials once rather than reading the store for every request
+	creds, err := r.credentials()
+	if err != nil {
+		for i := range results {
+			results[i] = BatchResult{Index: i, Err: err}
+		}
+		return results
+	}
+	q := *r
+	q.Auth = creds
+	q.Store = nil
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i].Index = i
+				results[i].Err = fn(&q, i)
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// GetAll gets entities concurrently with at most workers requests in flight
+func (r *Request) GetAll(entities []EntityGetter, workers int) []BatchResult {
+	results := r.batch(len(entities), workers, func(q *Request, i int) error {
+		return q.Get(entities[i])
+	})
+	for i := range results {
+		results[i].Entity = entities[i]
+	}
+	return results
+}
+
+// PatchAll patches entities concurrently with at most workers requests in flight
+func (r *Request) PatchAll(entities []Entity, workers int) []BatchResult {
+	results := r.batch(len(entities), workers, func(q *Request, i int) error {
+		return q.Patch(entities[i])
+	})
+	for i := range results {
+		results[i].Entity = entities[i]
+	}
+	return results
+}
+
+// DeleteAll deletes entities concurrently with at most workers requests in flight
+func (r *Request) DeleteAll(entities []Entity, workers int) []BatchResult {
+	results := r.batch(len(entities), workers, func(q *Request, i int) error {
+		return q.Delete(entities[i])
+	})
+	for i := range results {
+		results[i].Entity = entities[i]
+	}
+	return results
+}
+
+// BatchErrors returns the results that failed
+func BatchErrors(results []BatchResult) []BatchResult {
+	var failed []BatchResult
+	for _, res := range results {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// bulkItem is the per item response eve returns for a bulk insert
+type bulkItem struct {
+	Status string                 `json:"_status"`
+	Issues map[string]interface{} `json:"_issues"`
+}
+
+// PostAll creates entities with a single eve bulk insert. All entities must share the
+// same POST path. Eve rejects the whole insert if any item is invalid, so when one
+// item fails every item's result carries an error.
+func (r *Request) PostAll(entities []Entity) ([]BatchResult, error) {
+	results := make([]BatchResult, len(entities))
+	if len(entities) == 0 {
+		return results, nil
+	}
+
+	entityPath := entities[0].Path("POST")
+	var payload []json.RawMessage
+	invalid := 0
+	var known map[string]bool // templates, fetched once the first templated deploy is found
+	var err error
+	for i, e := range entities {
+		if e.Path("POST") != entityPath {
+			return nil, fmt.Errorf("Cannot bulk post to %s and %s", entityPath, e.Path("POST"))
+		}
+		payload = append(payload, json.RawMessage(e.JSON()))
+		results[i] = BatchResult{Index: i, Entity: e}
+
+		results[i].Err = validate(e, "POST")
+		if deploys := newDeploys(e); results[i].Err == nil && deploys != nil {
+			if known == nil {
+				known, err = r.templateNames()
+				if err != nil {
+					return nil, err
+				}
+			}
+			results[i].Err = templateErrors(known, deploys, 0)
+		}
+		if results[i].Err != nil {
+			invalid++
+		}
+	}
+	if invalid > 0 {
+		return results, fmt.Errorf("%d of %d items failed validation", invalid, len(entities))
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(r.Host)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, entityPath)
+
+	req, err := http.NewRequest("POST", u.String(), bytes.NewBuffer(body))
+	if err != nil {
+		return nil, errors.New("Error creating NewRequest: " + err.Error())
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	err = r.authorize(req)
+	if err != nil {
+		return nil, err
+	}
+
+	client := r.httpClient()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer resp.Body.Close()
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var bulk struct {
+		Items []json.RawMessage `json:"_items"`
+	}
+	json.Unmarshal(respBody, &bulk)
+
+	if len(bulk.Items) != len(entities) {
+		if resp.StatusCode-200 > 100 {
+			log.Println(u.String())
+			log.Println(redact(respBody))
+			return nil, &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
+		}
+		return nil, fmt.Errorf("Expected %d items in bulk response, got %d", len(entities), len(bulk.Items))
+	}
+
+	failed := resp.StatusCode-200 > 100
+	for i, raw := range bulk.Items {
+		var item bulkItem
+		json.Unmarshal(raw, &item)
+
+		switch {
+		case item.Status == "ERR":
+			results[i].Err = issuesError(item.Issues)
+		case failed:
+			results[i].Err = fmt.Errorf("Not created, another item in the batch failed: %d", resp.StatusCode)
+		default:
+			results[i].Err = entities[i].Unmarshal(raw)
+		}
+	}
+
+	if failed {
+		return results, &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
+	}
+	r.invalidate(entityPath)
+	return results, nil
+}
+
+// issuesError turns eve's _issues into an error
+func issuesError(issues map[string]interface{}) error {
+	if len(issues) == 0 {
+		return errors.New("item rejected")
+	}
+
+	var fields []string
+	for field := range issues {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	var msgs []string
+	for _, field := range fields {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", field, issues[field]))
+	}
+	return errors.New(strings.Join(msgs, ", "))
+}
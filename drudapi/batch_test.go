@@ -0,0 +1,151 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetAll(t *testing.T) {
+	var lock sync.Mutex
+	inFlight, maxInFlight := 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		lock.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+
+		appID := strings.TrimPrefix(r.URL.Path, "/application/")
+		if appID == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"app_id": "%s", "name": "name-%s"}`, appID, appID)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+		Auth: &Credentials{
+			AdminToken: "dfgdfg",
+		},
+	}
+
+	var entities []EntityGetter
+	for _, id := range []string{"a", "b", "missing", "c", "d", "e"} {
+		entities = append(entities, &Application{AppID: id})
+	}
+
+	results := r.GetAll(entities, 2)
+	expect(t, len(results), 6)
+	expect(t, maxInFlight <= 2, true)
+
+	for i, res := range results {
+		expect(t, res.Index, i)
+		app := res.Entity.(*Application)
+		if app.AppID == "missing" {
+			refute(t, res.Err, nil)
+			continue
+		}
+		expect(t, res.Err, nil)
+		expect(t, app.Name, "name-"+app.AppID)
+	}
+
+	failed := BatchErrors(results)
+	expect(t, len(failed), 1)
+	expect(t, failed[0].Index, 2)
+}
+
+func TestDeleteAll(t *testing.T) {
+	var lock sync.Mutex
+	deleted := make(map[string]string)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.Method, "DELETE")
+		lock.Lock()
+		deleted[r.URL.Path] = r.Header.Get("If-Match")
+		lock.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	results := r.DeleteAll([]Entity{
+		&Client{Name: "one", Etag: "e1"},
+		&Client{Name: "two", Etag: "e2"},
+	}, 0)
+
+	expect(t, len(BatchErrors(results)), 0)
+	expect(t, deleted["/client/one"], "e1")
+	expect(t, deleted["/client/two"], "e2")
+}
+
+func TestPostAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.Method, "POST")
+		expect(t, r.URL.Path, "/client")
+
+		var posted []Client
+		body, _ := ioutil.ReadAll(r.Body)
+		err := json.Unmarshal(body, &posted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect(t, len(posted), 2)
+
+		if posted[1].Name == "one" {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"_status": "ERR", "_items": [
+				{"_status": "OK"},
+				{"_status": "ERR", "_issues": {"name": "value 'one' is not unique"}}
+			]}`)
+			return
+		}
+		fmt.Fprint(w, `{"_status": "OK", "_items": [
+			{"_status": "OK", "_id": "id1", "_etag": "e1"},
+			{"_status": "OK", "_id": "id2", "_etag": "e2"}
+		]}`)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	one := &Client{Name: "one"}
+	two := &Client{Name: "two"}
+	results, err := r.PostAll([]Entity{one, two})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(BatchErrors(results)), 0)
+	expect(t, one.ID, "id1")
+	expect(t, two.Etag, "e2")
+
+	results, err = r.PostAll([]Entity{&Client{Name: "three"}, &Client{Name: "one"}})
+	refute(t, err, nil)
+	refute(t, results[0].Err, nil)
+	expect(t, results[1].Err.Error(), "name: value 'one' is not unique")
+
+	_, err = r.PostAll([]Entity{&Client{Name: "four"}, &Application{}})
+	refute(t, err, nil)
+}
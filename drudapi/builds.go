@@ -1,28 +1,55 @@
 package drudapi
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// Build states reported by the api
+const (
+	BuildStateSuccess         = "success"
+	BuildStateFailed          = "failed"
+	BuildStateCancelRequested = "cancel_requested"
+	BuildStateCancelled       = "cancelled"
+)
 
 // Build ...
 type Build struct {
-	Created     string      `json:"_created,omitempty"`
-	Etag        string      `json:"_etag,omitempty"`
-	ID          string      `json:"_id,omitempty"`
-	Updated     string      `json:"_updated,omitempty"`
-	Type        string      `json:"type,omitempty"`
-	Name        string      `json:"name,omitempty"`
-	CloneURL    string      `json:"clone_url,omitempty"`
-	Registry    string      `json:"registry,omitempty"`
-	ImageName   string      `json:"image_name,omitempty"`
-	Branch      string      `json:"branch,omitempty"`
-	DeployName  string      `json:"deploy_name,omitempty"`
-	State       string      `json:"state,omitempty"`
-	Logs        string      `json:"logs,omitempty"`
-	Build       int         `json:"build,omitempty"`
-	Client      Client      `json:"client,omitempty"`
-	Template    string      `json:"template,omitempty"`
-	Application Application `json:"application,omitempty"`
-	Container   Container   `json:"container,omitempty"`
-	TagName     string      `json:"tag_name,omitempty"`
+	Created      string      `json:"_created,omitempty"`
+	Etag         string      `json:"_etag,omitempty"`
+	ID           string      `json:"_id,omitempty"`
+	Updated      string      `json:"_updated,omitempty"`
+	Type         string      `json:"type,omitempty"`
+	Name         string      `json:"name,omitempty"`
+	CloneURL     string      `json:"clone_url,omitempty"`
+	Registry     string      `json:"registry,omitempty"`
+	ImageName    string      `json:"image_name,omitempty"`
+	Branch       string      `json:"branch,omitempty"`
+	DeployName   string      `json:"deploy_name,omitempty"`
+	State        string      `json:"state,omitempty"`
+	Logs         string      `json:"logs,omitempty"`
+	Build        int         `json:"build,omitempty"`
+	Client       Client      `json:"client,omitempty"`
+	Template     string      `json:"template,omitempty"`
+	Application  Application `json:"application,omitempty"`
+	Container    Container   `json:"container,omitempty"`
+	TagName      string      `json:"tag_name,omitempty"`
+	PromotedFrom string      `json:"promoted_from,omitempty"`
+	RetryOf      string      `json:"retry_of,omitempty"`
+	// Extra holds fields returned by the api that Build does not know about
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the Build and keeps any fields it does not know about in Extra
+func (b *Build) UnmarshalJSON(data []byte) error {
+	type plain Build
+	return unmarshalWithExtra(data, (*plain)(b), &b.Extra)
+}
+
+// MarshalJSON encodes the Build along with the fields kept in Extra
+func (b Build) MarshalJSON() ([]byte, error) {
+	type plain Build
+	return marshalWithExtra(plain(b), b.Extra)
 }
 
 // Path ...
@@ -72,6 +99,23 @@ func (b Build) ETAG() string {
 	return b.Etag
 }
 
+// Validate checks the build's required fields
+func (b Build) Validate(method string) error {
+	v := &validation{}
+	if method == "POST" {
+		v.required("name", b.Name)
+	}
+	if b.DeployName != "" && !IsDNSLabel(b.DeployName) {
+		v.add("deploy_name", "%q must be lowercase letters, numbers and hyphens", b.DeployName)
+	}
+	return v.err()
+}
+
+// Finished returns true once the build has succeeded, failed or been cancelled
+func (b Build) Finished() bool {
+	return b.State == BuildStateSuccess || b.State == BuildStateFailed || b.State == BuildStateCancelled
+}
+
 // BuildList ...
 type BuildList struct {
 	Items []Build `json:"_items"`
@@ -92,3 +136,53 @@ func (b *BuildList) Unmarshal(data []byte) error {
 	err := json.Unmarshal(data, &b)
 	return err
 }
+
+// ListBuilds returns the builds for an application's deploy, newest first. If state
+// is not empty only builds in that state are returned.
+func (r *Request) ListBuilds(app *Application, deployName string, state string) (*BuildList, error) {
+	where := map[string]interface{}{
+		"application": app.ID,
+		"deploy_name": deployName,
+	}
+	if state != "" {
+		where["state"] = state
+	}
+
+	query, err := whereQuery(where)
+	if err != nil {
+		return nil, err
+	}
+
+	q := *r
+	q.Query = query + "&sort=-_created"
+
+	builds := &BuildList{}
+	err = q.Get(builds)
+	if err != nil {
+		return nil, err
+	}
+	return builds, nil
+}
+
+// whereQuery encodes filter as an eve where query param
+func whereQuery(filter map[string]interface{}) (string, error) {
+	jbytes, err := json.Marshal(filter)
+	if err != nil {
+		return "", err
+	}
+	return "where=" + url.QueryEscape(string(jbytes)), nil
+}
+
+// LatestBuild returns the newest build for an application's deploy, optionally
+// limited to builds in state. It returns nil if there are no matching builds.
+func (r *Request) LatestBuild(app *Application, deployName string, state string) (*Build, error) {
+	builds, err := r.ListBuilds(app, deployName, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(builds.Items) == 0 {
+		return nil, nil
+	}
+	return &builds.Items[0], nil
+}
@@ -1,7 +1,11 @@
 package drudapi
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 )
 
@@ -96,3 +100,31 @@ func TestPostBuild(t *testing.T) {
 	expect(t, bd.Logs, "booo!")
 
 }
+
+func TestListBuildsFilter(t *testing.T) {
+	var where map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := json.Unmarshal([]byte(r.URL.Query().Get("where")), &where)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		expect(t, r.URL.Query().Get("sort"), "-_created")
+		fmt.Fprint(w, `{"_items": [{"name": "b1"}]}`)
+	}))
+	defer server.Close()
+
+	r := Request{Host: server.URL, Auth: &Credentials{AdminToken: "dgdfg"}}
+
+	// quotes in a deploy name must not break out of the filter
+	builds, err := r.ListBuilds(&Application{ID: "app1"}, `default","state":"failed`, "success")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, len(builds.Items), 1)
+	expect(t, len(where), 3)
+	expect(t, where["application"], "app1")
+	expect(t, where["deploy_name"], `default","state":"failed`)
+	expect(t, where["state"], "success")
+}
@@ -0,0 +1,278 @@
+package drudapi
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a cached api response
+type CacheEntry struct {
+	URL      string          `json:"url"`
+	Identity string          `json:"identity,omitempty"` // Cache.Identity of the credentials it was fetched with
+	Path     string          `json:"path"`               // entity path the response was fetched from
+	Etag     string          `json:"etag,omitempty"`
+	Body     json.RawMessage `json:"body"`
+	Fetched  time.Time       `json:"fetched"`
+}
+
+// Cache stores api responses on disk so Get can revalidate them with If-None-Match
+// and fall back to them when the api is unreachable
+type Cache struct {
+	Dir  string
+	lock sync.Mutex
+}
+
+// DefaultCache returns a cache at drud/api in the user's cache dir
+func DefaultCache() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cache{
+		Dir: filepath.Join(dir, "drud", "api"),
+	}, nil
+}
+
+// Identity returns the identity responses fetched with creds are cached under,
+// so users sharing a cache never see each other's responses. It is an hmac of the
+// credentials keyed with a random key kept in the cache dir, so the entries on
+// disk can't be used to guess the credentials.
+func (c *Cache) Identity(creds *Credentials) (string, error) {
+	if creds == nil {
+		return "", nil
+	}
+
+	key, err := c.key()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(creds.AdminToken + "\n" + creds.Token + "\n" + creds.Username + "\n" + creds.Password))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// key returns the key identities are hashed with, creating it the first time.
+// The key is only readable by the current user.
+func (c *Cache) key() ([]byte, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	f := filepath.Join(c.Dir, "identity.key")
+	key, err := ioutil.ReadFile(f)
+	if err == nil {
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	err = os.MkdirAll(c.Dir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	key = make([]byte, 32)
+	_, err = rand.Read(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// another process may create the key first, in which case use theirs
+	out, err := os.OpenFile(f, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return ioutil.ReadFile(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = out.Write(key)
+	out.Close()
+	if err != nil {
+		os.Remove(f)
+		return nil, err
+	}
+	return key, nil
+}
+
+// cacheable returns body as it may be written to disk, with the values of any
+// secretFields removed, and whether any were. User responses are never cached
+// and return nil.
+func cacheable(entityPath string, body []byte) ([]byte, bool) {
+	if resource(entityPath) == "users" {
+		return nil, false
+	}
+
+	var doc interface{}
+	if json.Unmarshal(body, &doc) != nil {
+		return nil, false
+	}
+	if !stripSecrets(doc) {
+		return body, false
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// file returns where the entry for url fetched as identity is stored
+func (c *Cache) file(url string, identity string) string {
+	sum := sha1.Sum([]byte(identity + "\n" + url))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Load returns the entry cached for url fetched as identity or nil if there is none
+func (c *Cache) Load(url string, identity string) *CacheEntry {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	data, err := ioutil.ReadFile(c.file(url, identity))
+	if err != nil {
+		return nil
+	}
+
+	entry := &CacheEntry{}
+	err = json.Unmarshal(data, entry)
+	if err != nil || entry.URL != url || entry.Identity != identity {
+		return nil
+	}
+	return entry
+}
+
+// Save stores entry, replacing any entry for the same url and identity. Entries are
+// only readable by the current user.
+func (c *Cache) Save(entry *CacheEntry) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err := os.MkdirAll(c.Dir, 0700)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	// write then rename so a concurrent Load never sees a partial entry
+	tmp, err := ioutil.TempFile(c.Dir, "entry")
+	if err != nil {
+		return err
+	}
+	err = tmp.Chmod(0600)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.file(entry.URL, entry.Identity))
+}
+
+// entries returns every entry in the cache along with the file it is stored in
+func (c *Cache) entries() (map[string]*CacheEntry, error) {
+	files, err := filepath.Glob(filepath.Join(c.Dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]*CacheEntry)
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			continue
+		}
+		entry := &CacheEntry{}
+		if json.Unmarshal(data, entry) == nil {
+			entries[f] = entry
+		}
+	}
+	return entries, nil
+}
+
+// resource returns the collection an entity path belongs to e.g. application for
+// application/myapp
+func resource(entityPath string) string {
+	return strings.SplitN(strings.Trim(entityPath, "/"), "/", 2)[0]
+}
+
+// Invalidate removes every cached response from the same collection as entityPath,
+// both the items and the lists that could include them
+func (c *Cache) Invalidate(entityPath string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+
+	collection := resource(entityPath)
+	for f, entry := range entries {
+		if resource(entry.Path) == collection {
+			err = os.Remove(f)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Clear removes every cached response
+func (c *Cache) Clear() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err := os.RemoveAll(c.Dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// invalidate clears the cache after entityPath has been changed through the api
+func (r *Request) invalidate(entityPath string) {
+	if r.Cache == nil {
+		return
+	}
+	err := r.Cache.Invalidate(entityPath)
+	if err != nil {
+		log.Printf("Could not invalidate cached %s: %s", entityPath, err)
+	}
+}
+
+// responseEtag returns the etag of a response from its ETag header or the _etag of the
+// body, quoted ready to send as If-None-Match
+func responseEtag(header string, body []byte) string {
+	if header != "" {
+		return header
+	}
+
+	var meta struct {
+		Etag string `json:"_etag"`
+	}
+	json.Unmarshal(body, &meta)
+	if meta.Etag == "" {
+		return ""
+	}
+	return `"` + meta.Etag + `"`
+}
@@ -0,0 +1,251 @@
+package drudapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCacheRevalidatesAndServesOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drudapi-cache")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	etag := "etag1"
+	fetches, notModified := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			fetches++
+			if r.Header.Get("If-None-Match") == `"`+etag+`"` {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			fmt.Fprintf(w, `{"app_id": "myapp", "name": "My App %s", "_etag": "%s"}`, etag, etag)
+		case "PATCH":
+			etag = "etag2"
+			fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+		}
+	}))
+
+	r := Request{
+		Host:  server.URL,
+		Cache: &Cache{Dir: dir},
+	}
+
+	app := &Application{AppID: "myapp"}
+	err = r.Get(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, app.Name, "My App etag1")
+
+	app = &Application{AppID: "myapp"}
+	err = r.Get(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, app.Name, "My App etag1")
+	expect(t, fetches, 2)
+	expect(t, notModified, 1)
+
+	// patching invalidates the cached application so the next get is a full fetch
+	err = r.Patch(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, r.Cache.Load(server.URL+"/application/myapp", "") == nil, true)
+
+	app = &Application{AppID: "myapp"}
+	err = r.Get(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, app.Name, "My App etag2")
+	expect(t, notModified, 1)
+
+	server.Close()
+
+	app = &Application{AppID: "myapp"}
+	err = r.Get(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, app.Name, "My App etag2")
+
+	err = r.Get(&Application{AppID: "other"})
+	refute(t, err, nil)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drudapi-cache")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := &Cache{Dir: dir}
+	entries := []*CacheEntry{
+		{URL: "http://api/application", Path: "application", Body: []byte(`{}`)},
+		{URL: "http://api/application/myapp", Path: "application/myapp", Body: []byte(`{}`)},
+		{URL: "http://api/client/drud", Path: "client/drud", Body: []byte(`{}`)},
+	}
+	for _, e := range entries {
+		err = c.Save(e)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = c.Invalidate("application/myapp")
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, c.Load("http://api/application", "") == nil, true)
+	expect(t, c.Load("http://api/application/myapp", "") == nil, true)
+	refute(t, c.Load("http://api/client/drud", "") == nil, true)
+
+	err = c.Clear()
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, c.Load("http://api/client/drud", "") == nil, true)
+}
+
+func TestCacheSeparatesCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drudapi-cache")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/fred":
+			fmt.Fprint(w, `{"username": "fred", "auth_token": "sometoken", "_etag": "etag1"}`)
+		case "/application/myapp":
+			fmt.Fprintf(w, `{"app_id": "myapp", "name": "%s", "_etag": "etag1"}`, r.Header.Get("Authorization"))
+		}
+	}))
+
+	cache := &Cache{Dir: dir}
+	fred := Request{Host: server.URL, Cache: cache, Auth: &Credentials{Token: "fredtoken"}}
+	admin := Request{Host: server.URL, Cache: cache, Auth: &Credentials{AdminToken: "admintoken"}}
+
+	for _, r := range []Request{fred, admin} {
+		err = r.Get(&Application{AppID: "myapp"})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	err = fred.Get(&User{Username: "fred"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.Close()
+
+	// each set of credentials only falls back to its own responses
+	app := &Application{AppID: "myapp"}
+	err = fred.Get(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, app.Name, "Bearer fredtoken")
+
+	app = &Application{AppID: "myapp"}
+	err = admin.Get(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, app.Name, "token admintoken")
+
+	anonymous := Request{Host: server.URL, Cache: cache}
+	refute(t, anonymous.Get(&Application{AppID: "myapp"}), nil)
+
+	// users are never written to disk
+	refute(t, fred.Get(&User{Username: "fred"}), nil)
+
+	// identities are keyed per cache so they can't be matched against known credentials
+	id1, err := cache.Identity(fred.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+	id2, err := cache.Identity(fred.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, id1, id2)
+	other, err := (&Cache{Dir: dir + "/other"}).Identity(fred.Auth)
+	if err != nil {
+		log.Fatal(err)
+	}
+	refute(t, other, id1)
+	os.RemoveAll(dir + "/other")
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	// the two applications and the identity key
+	expect(t, len(files), 3)
+	for _, f := range files {
+		expect(t, f.Mode().Perm(), os.FileMode(0600))
+	}
+}
+
+func TestCacheStripsSecrets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drudapi-cache")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.Header.Get("If-None-Match") != "" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fmt.Fprint(w, `{"app_id": "myapp", "name": "My App", "_etag": "etag1",
+			"deploys": [{"name": "default", "basicauth_pass": "secret", "auth_key": "key", "nonce_salt": "salt"}]}`)
+	}))
+
+	r := Request{Host: server.URL, Cache: &Cache{Dir: dir}}
+
+	// online every get is a full fetch so the secrets are never lost to a 304
+	for i := 0; i < 2; i++ {
+		app := &Application{AppID: "myapp"}
+		err = r.Get(app)
+		if err != nil {
+			log.Fatal(err)
+		}
+		expect(t, app.Deploys[0].BasicAuthPass, "secret")
+	}
+	expect(t, fetches, 2)
+
+	entry := r.Cache.Load(server.URL+"/application/myapp", "")
+	refute(t, entry == nil, true)
+	expect(t, entry.Etag, "")
+	expect(t, strings.Contains(string(entry.Body), "secret"), false)
+	expect(t, strings.Contains(string(entry.Body), "auth_key"), false)
+	expect(t, strings.Contains(string(entry.Body), "nonce_salt"), false)
+
+	// offline the application is served without them
+	server.Close()
+	app := &Application{AppID: "myapp"}
+	err = r.Get(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, app.Name, "My App")
+	expect(t, app.Deploys[0].BasicAuthPass, "")
+}
@@ -0,0 +1,132 @@
+package drudapi
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/drud/drud-go/utils/network"
+	"github.com/gosuri/uitable"
+)
+
+// RenewalThreshold is how close to expiry a certificate is flagged for renewal
+var RenewalThreshold = 30 * 24 * time.Hour
+
+// CertificateReport is the certificate of a single https deploy
+type CertificateReport struct {
+	AppID       string
+	Deploy      string
+	Certificate *network.CertificateInfo // nil if it could not be inspected
+	Renew       bool                     // expires within the threshold
+	Err         error
+}
+
+// CheckCertificate connects to an https deploy and describes its certificate, which is
+// verified against roots or the system roots when nil
+func (d Deploy) CheckCertificate(timeout time.Duration, roots *x509.CertPool) (*network.CertificateInfo, error) {
+	if d.Protocol != "https" {
+		return nil, fmt.Errorf("Deploy %s is not served over https", d.Name)
+	}
+
+	u, err := url.Parse(d.SiteURL())
+	if err != nil {
+		return nil, err
+	}
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "443")
+	}
+	return network.InspectCertificate(address, u.Hostname(), roots, timeout)
+}
+
+// CheckCertificates inspects the certificate of every https deploy of the application
+// and flags those that expire within threshold
+func (a *Application) CheckCertificates(threshold time.Duration, timeout time.Duration, roots *x509.CertPool) []CertificateReport {
+	var reports []CertificateReport
+	for _, d := range a.Deploys {
+		if d.Protocol != "https" {
+			continue
+		}
+
+		report := CertificateReport{
+			AppID:  a.AppID,
+			Deploy: d.Name,
+		}
+		report.Certificate, report.Err = d.CheckCertificate(timeout, roots)
+		if report.Certificate != nil {
+			report.Renew = report.Certificate.ExpiresWithin(threshold)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// CheckAllCertificates runs CheckCertificates for every application with at most
+// workers applications checked at once
+func (r *Request) CheckAllCertificates(threshold time.Duration, timeout time.Duration, roots *x509.CertPool, workers int) ([]CertificateReport, error) {
+	apps, err := r.AllApplications()
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([][]CertificateReport, len(apps))
+	r.batch(len(apps), workers, func(q *Request, i int) error {
+		reports[i] = apps[i].CheckCertificates(threshold, timeout, roots)
+		return nil
+	})
+
+	var all []CertificateReport
+	for _, report := range reports {
+		all = append(all, report...)
+	}
+	return all, nil
+}
+
+// DueForRenewal returns the reports whose certificates expire within the threshold
+// they were checked with
+func DueForRenewal(reports []CertificateReport) []CertificateReport {
+	var due []CertificateReport
+	for _, report := range reports {
+		if report.Renew {
+			due = append(due, report)
+		}
+	}
+	return due
+}
+
+// DescribeCertificates pretty prints certificate reports
+func DescribeCertificates(reports []CertificateReport) {
+	fmt.Printf("%v %v checked, %v due for renewal.\n\n", len(reports), FormatPlural(len(reports), "certificate", "certificates"), len(DueForRenewal(reports)))
+
+	table := uitable.New()
+	table.MaxColWidth = 50
+	table.AddRow("APP", "DEPLOY", "ISSUER", "EXPIRES", "DAYS", "RENEW", "PROBLEMS")
+	for _, report := range reports {
+		c := report.Certificate
+		if c == nil {
+			table.AddRow(report.AppID, report.Deploy, "", "", "", "", report.Err.Error())
+			continue
+		}
+
+		var renew, problems string
+		if report.Renew {
+			renew = "✓"
+		}
+		if c.HostnameMismatch {
+			problems = fmt.Sprintf("not valid for %s ", c.ServerName)
+		}
+		problems += c.ChainError
+		table.AddRow(
+			report.AppID,
+			report.Deploy,
+			c.Issuer,
+			c.NotAfter.Format("2006-01-02"),
+			c.DaysToExpiry(time.Now()),
+			renew,
+			problems,
+		)
+	}
+	fmt.Println(table)
+}
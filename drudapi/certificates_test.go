@@ -0,0 +1,143 @@
+package drudapi
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tlsTestServer serves https with a self signed certificate for localhost that
+// expires after validFor
+func tlsTestServer(validFor time.Duration) (*httptest.Server, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost", Organization: []string{"Drud Test"}},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validFor),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		log.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	// inspecting closes the connection straight after the handshake
+	server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	server.StartTLS()
+	return server, cert
+}
+
+func TestCheckCertificate(t *testing.T) {
+	server, cert := tlsTestServer(10 * 24 * time.Hour)
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+	d := Deploy{Name: "production", Protocol: "https", Url: "localhost:" + port}
+
+	info, err := d.CheckCertificate(time.Second, roots)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, info.ServerName, "localhost")
+	expect(t, len(info.SANs), 1)
+	expect(t, info.SANs[0], "localhost")
+	expect(t, info.Issuer, "CN=localhost,O=Drud Test")
+	expect(t, len(info.Chain), 1)
+	expect(t, info.HostnameMismatch, false)
+	expect(t, info.ChainError, "")
+	expect(t, info.DaysToExpiry(time.Now()), 9)
+	expect(t, info.Valid(), true)
+
+	d.Url = "127.0.0.1:" + port
+	info, err = d.CheckCertificate(time.Second, roots)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, info.HostnameMismatch, true)
+	expect(t, info.Valid(), false)
+
+	d.Url = "localhost:" + port
+	info, err = d.CheckCertificate(time.Second, x509.NewCertPool())
+	if err != nil {
+		log.Fatal(err)
+	}
+	refute(t, info.ChainError, "")
+
+	d.Protocol = "http"
+	_, err = d.CheckCertificate(time.Second, roots)
+	refute(t, err, nil)
+}
+
+func TestCheckAllCertificates(t *testing.T) {
+	expiring, cert := tlsTestServer(10 * 24 * time.Hour)
+	defer expiring.Close()
+	current, _ := tlsTestServer(90 * 24 * time.Hour)
+	defer current.Close()
+
+	port := func(s *httptest.Server) string {
+		return s.URL[strings.LastIndex(s.URL, ":")+1:]
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	server := getTestServer(200, fmt.Sprintf(`{"_items": [
+		{"app_id": "one", "deploys": [
+			{"name": "production", "protocol": "https", "url": "localhost:%s"},
+			{"name": "staging", "protocol": "http", "url": "localhost:1"}
+		]},
+		{"app_id": "two", "deploys": [
+			{"name": "production", "protocol": "https", "url": "localhost:%s"}
+		]}
+	], "_meta": {"total": 2}}`, port(expiring), port(current)))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	reports, err := r.CheckAllCertificates(RenewalThreshold, time.Second, roots, 2)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(reports), 2)
+	expect(t, reports[0].Err, nil)
+	expect(t, reports[1].Err, nil)
+
+	due := DueForRenewal(reports)
+	expect(t, len(due), 1)
+	expect(t, due[0].AppID, "one")
+	expect(t, due[0].Deploy, "production")
+}
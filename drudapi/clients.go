@@ -17,6 +17,55 @@ type Client struct {
 	Name    string `json:"name,omitempty"`
 	Phone   string `json:"phone,omitempty"`
 	RepoOrg string `json:"repo_org,omitempty"`
+	// Extra holds fields returned by the api that Client does not know about
+	Extra    map[string]json.RawMessage `json:"-"`
+	relation relation
+}
+
+// UnmarshalJSON decodes the Client and keeps any fields it does not know about in Extra.
+// A bare _id is decoded as a reference.
+func (c *Client) UnmarshalJSON(data []byte) error {
+	ref, err := decodeReference(data, &c.ID)
+	if ref || err != nil {
+		c.relation = relationReference
+		return err
+	}
+
+	type plain Client
+	err = unmarshalWithExtra(data, (*plain)(c), &c.Extra)
+	if err != nil {
+		return err
+	}
+
+	c.relation = relationEmbedded
+	return nil
+}
+
+// MarshalJSON encodes the Client along with the fields kept in Extra. References are
+// encoded as their _id.
+func (c Client) MarshalJSON() ([]byte, error) {
+	if c.relation == relationReference {
+		return json.Marshal(c.ID)
+	}
+
+	type plain Client
+	return marshalWithExtra(plain(c), c.Extra)
+}
+
+// ClientRef returns a reference to the client with the given _id, which is sent as the
+// bare _id e.g. when posting an application
+func ClientRef(id string) Client {
+	return Client{ID: id, relation: relationReference}
+}
+
+// IsReference returns true when the api returned only the client's _id
+func (c Client) IsReference() bool {
+	return c.relation == relationReference
+}
+
+// IsEmbedded returns true when the api returned the full client
+func (c Client) IsEmbedded() bool {
+	return c.relation == relationEmbedded
 }
 
 // Path ...
@@ -27,6 +76,10 @@ func (c Client) Path(method string) string {
 		path = "client"
 	} else {
 		path = "client/" + c.Name
+		// references only know the _id which eve also accepts as the item lookup
+		if c.Name == "" {
+			path = "client/" + c.ID
+		}
 	}
 	return path
 }
@@ -66,6 +119,15 @@ func (c Client) ETAG() string {
 	return c.Etag
 }
 
+// Validate checks the client's required fields
+func (c Client) Validate(method string) error {
+	v := &validation{}
+	if method == "POST" {
+		v.required("name", c.Name)
+	}
+	return v.err()
+}
+
 // ClientList ...
 type ClientList struct {
 	Items []Client `json:"_items"`
@@ -108,3 +170,28 @@ func (c *ClientList) Describe() {
 		}
 	}
 }
+
+// ClientNamesByID maps the _id of each client to its name, for labelling applications
+// that only reference their client
+func ClientNamesByID(clients []Client) map[string]string {
+	names := make(map[string]string)
+	for _, c := range clients {
+		names[c.ID] = c.Name
+	}
+	return names
+}
+
+// AllClients gets every page of clients
+func (r *Request) AllClients() ([]Client, error) {
+	var clients []Client
+	err := r.eachPage(ClientList{}.Path("GET"), "", func(data []byte) error {
+		list := &ClientList{}
+		err := list.Unmarshal(data)
+		clients = append(clients, list.Items...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
@@ -13,6 +13,55 @@ type Container struct {
 	Branch       string `json:"branch,omitempty"`
 	GithubHookID int    `json:"github_hook_id,omitempty"`
 	Client       Client `json:"client,omitempty"`
+	// Extra holds fields returned by the api that Container does not know about
+	Extra    map[string]json.RawMessage `json:"-"`
+	relation relation
+}
+
+// UnmarshalJSON decodes the Container and keeps any fields it does not know about in Extra.
+// A bare _id is decoded as a reference.
+func (c *Container) UnmarshalJSON(data []byte) error {
+	ref, err := decodeReference(data, &c.ID)
+	if ref || err != nil {
+		c.relation = relationReference
+		return err
+	}
+
+	type plain Container
+	err = unmarshalWithExtra(data, (*plain)(c), &c.Extra)
+	if err != nil {
+		return err
+	}
+
+	c.relation = relationEmbedded
+	return nil
+}
+
+// MarshalJSON encodes the Container along with the fields kept in Extra. References are
+// encoded as their _id.
+func (c Container) MarshalJSON() ([]byte, error) {
+	if c.relation == relationReference {
+		return json.Marshal(c.ID)
+	}
+
+	type plain Container
+	return marshalWithExtra(plain(c), c.Extra)
+}
+
+// ContainerRef returns a reference to the container with the given _id, which is sent
+// as the bare _id
+func ContainerRef(id string) Container {
+	return Container{ID: id, relation: relationReference}
+}
+
+// IsReference returns true when the api returned only the container's _id
+func (c Container) IsReference() bool {
+	return c.relation == relationReference
+}
+
+// IsEmbedded returns true when the api returned the full container
+func (c Container) IsEmbedded() bool {
+	return c.relation == relationEmbedded
 }
 
 // Path ...
@@ -62,6 +111,15 @@ func (c Container) ETAG() string {
 	return c.Etag
 }
 
+// Validate checks the container's required fields
+func (c Container) Validate(method string) error {
+	v := &validation{}
+	if method == "POST" {
+		v.required("name", c.Name)
+	}
+	return v.err()
+}
+
 // ContainerList ...
 type ContainerList struct {
 	Items []Container `json:"_items"`
@@ -0,0 +1,267 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	vaultAPI "github.com/hashicorp/vault/api"
+)
+
+// Kinds of deploy settings
+const (
+	SettingEnv  = "env"  // environment variable
+	SettingPHP  = "php"  // php.ini directive
+	SettingCron = "cron" // cron schedule for a named job
+)
+
+var envUnsafe = regexp.MustCompile(`[^A-Z0-9_]`)
+
+// Setting is a single piece of deploy configuration. Values can be set directly or
+// read from a vault secret so the secret is never stored by the api.
+type Setting struct {
+	Key        string `json:"key"`
+	Type       string `json:"type"`
+	Value      string `json:"value,omitempty"`
+	SecretPath string `json:"secret_path,omitempty"` // vault path the value is read from
+	SecretKey  string `json:"secret_key,omitempty"`  // key within the vault secret, defaults to value
+}
+
+// EnvName returns the environment variable the setting is exposed as. env settings
+// keep their key, php settings are prefixed with PHP_ and cron settings with CRON_.
+func (s Setting) EnvName() string {
+	name := strings.ToUpper(s.Key)
+	switch s.Type {
+	case SettingPHP:
+		name = "PHP_" + name
+	case SettingCron:
+		name = "CRON_" + name
+	}
+	return envUnsafe.ReplaceAllString(name, "_")
+}
+
+// DeployConfig holds the configuration of a single deploy of an application
+type DeployConfig struct {
+	Created    string    `json:"_created,omitempty"`
+	Etag       string    `json:"_etag,omitempty"`
+	ID         string    `json:"_id,omitempty"`
+	Updated    string    `json:"_updated,omitempty"`
+	AppID      string    `json:"app_id,omitempty"`
+	DeployName string    `json:"deploy_name,omitempty"`
+	Settings   []Setting `json:"settings"`
+	// Extra holds fields returned by the api that DeployConfig does not know about
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the DeployConfig and keeps any fields it does not know about in Extra
+func (c *DeployConfig) UnmarshalJSON(data []byte) error {
+	type plain DeployConfig
+	return unmarshalWithExtra(data, (*plain)(c), &c.Extra)
+}
+
+// MarshalJSON encodes the DeployConfig along with the fields kept in Extra
+func (c DeployConfig) MarshalJSON() ([]byte, error) {
+	type plain DeployConfig
+	return marshalWithExtra(plain(c), c.Extra)
+}
+
+// Path ...
+func (c DeployConfig) Path(method string) string {
+	var path string
+
+	if method == "POST" {
+		path = "deployconfig"
+	} else {
+		path = "deployconfig/" + c.AppID + "/" + c.DeployName
+	}
+	return path
+}
+
+// Unmarshal ...
+func (c *DeployConfig) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, &c)
+	return err
+}
+
+// JSON ...
+func (c DeployConfig) JSON() []byte {
+	c.ID = ""
+	c.Etag = ""
+	c.Created = ""
+	c.Updated = ""
+	if c.Settings == nil {
+		c.Settings = []Setting{}
+	}
+
+	jbytes, _ := json.Marshal(c)
+	return jbytes
+}
+
+// PatchJSON ...
+func (c DeployConfig) PatchJSON() []byte {
+	c.ID = ""
+	c.Etag = ""
+	c.Created = ""
+	c.Updated = ""
+	// app and deploy are the id params in drudapi and cannot be patched
+	c.AppID = ""
+	c.DeployName = ""
+	// always send the list so removing the last setting clears them in eve
+	if c.Settings == nil {
+		c.Settings = []Setting{}
+	}
+
+	jbytes, _ := json.Marshal(c)
+	return jbytes
+}
+
+// ETAG ...
+func (c DeployConfig) ETAG() string {
+	return c.Etag
+}
+
+// Validate checks the config identifies its deploy, that each setting is well formed
+// and that updates are protected by an etag
+func (c DeployConfig) Validate(method string) error {
+	v := &validation{}
+	v.required("app_id", c.AppID)
+	v.required("deploy_name", c.DeployName)
+	if method == "PATCH" {
+		v.required("_etag", c.Etag)
+	}
+
+	names := make(map[string]bool)
+	for i, s := range c.Settings {
+		field := fmt.Sprintf("settings[%d]", i)
+		if s.Key == "" {
+			v.add(field+".key", "is required")
+		}
+		if s.Type != SettingEnv && s.Type != SettingPHP && s.Type != SettingCron {
+			v.add(field+".type", "%q must be env, php or cron", s.Type)
+		}
+		if s.Value != "" && s.SecretPath != "" {
+			v.add(field+".value", "cannot be set along with secret_path")
+		}
+		if s.Key != "" && names[s.EnvName()] {
+			v.add(field+".key", "%q is set more than once", s.Key)
+		}
+		names[s.EnvName()] = true
+	}
+	return v.err()
+}
+
+// Get returns the setting with the given type and key
+func (c *DeployConfig) Get(settingType string, key string) *Setting {
+	for i := range c.Settings {
+		if c.Settings[i].Type == settingType && c.Settings[i].Key == key {
+			return &c.Settings[i]
+		}
+	}
+	return nil
+}
+
+// Set adds or replaces a setting
+func (c *DeployConfig) Set(s Setting) {
+	if existing := c.Get(s.Type, s.Key); existing != nil {
+		*existing = s
+		return
+	}
+	c.Settings = append(c.Settings, s)
+}
+
+// Remove deletes the setting with the given type and key
+func (c *DeployConfig) Remove(settingType string, key string) {
+	for i, s := range c.Settings {
+		if s.Type == settingType && s.Key == key {
+			c.Settings = append(c.Settings[:i], c.Settings[i+1:]...)
+			return
+		}
+	}
+}
+
+// SecretLookup returns the value stored under key at a vault path
+type SecretLookup func(path string, key string) (string, error)
+
+// VaultSecrets returns a SecretLookup that reads from vault
+func VaultSecrets(vault *vaultAPI.Logical) SecretLookup {
+	return func(path string, key string) (string, error) {
+		secret, err := vault.Read(path)
+		if err != nil {
+			return "", err
+		}
+		if secret == nil {
+			return "", fmt.Errorf("No secret at %s", path)
+		}
+
+		value, ok := secret.Data[key]
+		if !ok {
+			return "", fmt.Errorf("No %s in secret %s", key, path)
+		}
+		return fmt.Sprint(value), nil
+	}
+}
+
+// Environment resolves every setting, reading secret values with lookup, and returns
+// the final environment keyed by each setting's EnvName
+func (c *DeployConfig) Environment(lookup SecretLookup) (map[string]string, error) {
+	env := make(map[string]string)
+	for _, s := range c.Settings {
+		value := s.Value
+		if s.SecretPath != "" {
+			if lookup == nil {
+				return nil, errors.New("Secret lookup required to resolve " + s.Key)
+			}
+
+			key := s.SecretKey
+			if key == "" {
+				key = "value"
+			}
+
+			var err error
+			value, err = lookup(s.SecretPath, key)
+			if err != nil {
+				return nil, err
+			}
+		}
+		env[s.EnvName()] = value
+	}
+	return env, nil
+}
+
+// UpdateDeployConfig gets the config for a deploy, applies update and patches it. If
+// another client changes the config in between, the etag no longer matches and the
+// get and update are retried up to attempts times.
+func (r *Request) UpdateDeployConfig(appID string, deployName string, attempts int, update func(c *DeployConfig) error) (*DeployConfig, error) {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		c := &DeployConfig{
+			AppID:      appID,
+			DeployName: deployName,
+		}
+		err = r.Get(c)
+		if err != nil {
+			return nil, err
+		}
+
+		err = update(c)
+		if err != nil {
+			return nil, err
+		}
+
+		err = r.Patch(c)
+		if err == nil {
+			return c, nil
+		}
+		if !IsStatus(err, http.StatusPreconditionFailed) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,133 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeployConfigEnvironment(t *testing.T) {
+	c := &DeployConfig{AppID: "myapp", DeployName: "production"}
+	c.Set(Setting{Key: "APP_ENV", Type: SettingEnv, Value: "prod"})
+	c.Set(Setting{Key: "memory_limit", Type: SettingPHP, Value: "128M"})
+	c.Set(Setting{Key: "memory_limit", Type: SettingPHP, Value: "256M"})
+	c.Set(Setting{Key: "DB_PASSWORD", Type: SettingEnv, SecretPath: "secret/myapp/production/db", SecretKey: "password"})
+	c.Set(Setting{Key: "cleanup", Type: SettingCron, Value: "0 * * * *"})
+
+	expect(t, len(c.Settings), 4)
+	expect(t, c.Validate("POST"), nil)
+
+	lookups := 0
+	env, err := c.Environment(func(path string, key string) (string, error) {
+		lookups++
+		expect(t, path, "secret/myapp/production/db")
+		expect(t, key, "password")
+		return "hunter2", nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, lookups, 1)
+	expect(t, len(env), 4)
+	expect(t, env["APP_ENV"], "prod")
+	expect(t, env["PHP_MEMORY_LIMIT"], "256M")
+	expect(t, env["DB_PASSWORD"], "hunter2")
+	expect(t, env["CRON_CLEANUP"], "0 * * * *")
+
+	// the resolved secret is never part of the config sent to the api, only where to find it
+	for _, body := range [][]byte{c.JSON(), c.PatchJSON()} {
+		expect(t, strings.Contains(string(body), "hunter2"), false)
+
+		var sent DeployConfig
+		err = json.Unmarshal(body, &sent)
+		if err != nil {
+			log.Fatal(err)
+		}
+		secret := sent.Get(SettingEnv, "DB_PASSWORD")
+		expect(t, secret.Value, "")
+		expect(t, secret.SecretPath, "secret/myapp/production/db")
+	}
+
+	// removing every setting still patches an empty list
+	empty := DeployConfig{AppID: "myapp"}
+	expect(t, string(empty.PatchJSON()), `{"settings":[]}`)
+
+	_, err = c.Environment(func(path string, key string) (string, error) {
+		return "", errors.New("sealed")
+	})
+	refute(t, err, nil)
+
+	c.Remove(SettingCron, "cleanup")
+	expect(t, c.Get(SettingCron, "cleanup") == nil, true)
+}
+
+func TestDeployConfigValidate(t *testing.T) {
+	c := DeployConfig{
+		AppID:      "myapp",
+		DeployName: "production",
+		Settings: []Setting{
+			{Key: "A", Type: "yaml"},
+			{Key: "B", Type: SettingEnv, Value: "x", SecretPath: "secret/b"},
+		},
+	}
+
+	verr := c.Validate("PATCH").(ValidationError)
+	expect(t, len(verr), 3)
+	expect(t, verr[0].Field, "_etag")
+	expect(t, verr[1].Field, "settings[0].type")
+	expect(t, verr[2].Field, "settings[1].value")
+}
+
+func TestUpdateDeployConfigRetriesOnConflict(t *testing.T) {
+	etag := "etag1"
+	gets, patches := 0, 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.URL.Path, "/deployconfig/myapp/production")
+		switch r.Method {
+		case "GET":
+			gets++
+			fmt.Fprintf(w, `{"app_id": "myapp", "deploy_name": "production", "_etag": "%s", "settings": []}`, etag)
+			// someone else updates the config right after the first get
+			if gets == 1 {
+				etag = "etag2"
+			}
+		case "PATCH":
+			patches++
+			if r.Header.Get("If-Match") != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			var patched DeployConfig
+			json.Unmarshal(body, &patched)
+			expect(t, len(patched.Settings), 1)
+			expect(t, patched.AppID, "")
+			fmt.Fprint(w, `{"_etag": "etag3", "_status": "OK"}`)
+		}
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	c, err := r.UpdateDeployConfig("myapp", "production", 3, func(c *DeployConfig) error {
+		c.Set(Setting{Key: "APP_ENV", Type: SettingEnv, Value: "prod"})
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, gets, 2)
+	expect(t, patches, 2)
+	expect(t, c.Etag, "etag3")
+}
@@ -0,0 +1,114 @@
+package drudapi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gosuri/uitable"
+)
+
+// Reasons a deploy can have drifted from its latest build
+const (
+	DriftNeverBuilt      = "never built"
+	DriftFailing         = "failing"
+	DriftBranchChanged   = "branch changed"
+	DriftTemplateChanged = "template changed"
+)
+
+// DeployDrift lists the ways a deploy differs from its latest build
+type DeployDrift struct {
+	AppID    string
+	Deploy   Deploy
+	Build    *Build // nil if the deploy has never been built
+	Problems []string
+}
+
+// deployDrift compares a deploy with its latest build, b may be nil
+func deployDrift(app *Application, d Deploy, b *Build) DeployDrift {
+	drift := DeployDrift{
+		AppID:  app.AppID,
+		Deploy: d,
+		Build:  b,
+	}
+
+	if b == nil {
+		drift.Problems = append(drift.Problems, DriftNeverBuilt)
+		return drift
+	}
+	if b.State == BuildStateFailed {
+		drift.Problems = append(drift.Problems, DriftFailing)
+	}
+	if d.Branch != "" && b.Branch != "" && d.Branch != b.Branch {
+		drift.Problems = append(drift.Problems, DriftBranchChanged)
+	}
+	if d.Template != "" && b.Template != "" && d.Template != b.Template {
+		drift.Problems = append(drift.Problems, DriftTemplateChanged)
+	}
+	return drift
+}
+
+// CheckDrift compares each deploy of app with its most recent build and returns the
+// deploys that are stale, failing or have never been built
+func (r *Request) CheckDrift(app *Application) ([]DeployDrift, error) {
+	var drifted []DeployDrift
+	for _, d := range app.Deploys {
+		b, err := r.LatestBuild(app, d.Name, "")
+		if err != nil {
+			return nil, err
+		}
+
+		drift := deployDrift(app, d, b)
+		if len(drift.Problems) > 0 {
+			drifted = append(drifted, drift)
+		}
+	}
+	return drifted, nil
+}
+
+// CheckAllDrift runs CheckDrift for every application with at most workers requests in flight
+func (r *Request) CheckAllDrift(workers int) ([]DeployDrift, error) {
+	apps, err := r.AllApplications()
+	if err != nil {
+		return nil, err
+	}
+
+	drifts := make([][]DeployDrift, len(apps))
+	results := r.batch(len(apps), workers, func(q *Request, i int) error {
+		var err error
+		drifts[i], err = q.CheckDrift(&apps[i])
+		return err
+	})
+	if failed := BatchErrors(results); len(failed) > 0 {
+		return nil, fmt.Errorf("Could not check drift for %s: %s", apps[failed[0].Index].AppID, failed[0].Err)
+	}
+
+	var drifted []DeployDrift
+	for _, d := range drifts {
+		drifted = append(drifted, d...)
+	}
+	return drifted, nil
+}
+
+// DescribeDrift pretty prints drifted deploys
+func DescribeDrift(drifted []DeployDrift) {
+	fmt.Printf("%v %v drifted.\n\n", len(drifted), FormatPlural(len(drifted), "deploy has", "deploys have"))
+
+	table := uitable.New()
+	table.MaxColWidth = 50
+	table.AddRow("APP", "DEPLOY", "BRANCH", "TEMPLATE", "LAST BUILD", "PROBLEMS")
+	for _, d := range drifted {
+		var built string
+		if d.Build != nil {
+			built = fmt.Sprintf("%s %s/%s", d.Build.State, d.Build.Branch, d.Build.Template)
+		}
+		table.AddRow(
+			d.AppID,
+			d.Deploy.Name,
+			d.Deploy.Branch,
+			d.Deploy.Template,
+			built,
+			strings.Join(d.Problems, ", "),
+		)
+	}
+	fmt.Println(table)
+}
@@ -0,0 +1,55 @@
+package drudapi
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckDrift(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		where := r.URL.Query().Get("where")
+		switch {
+		case strings.Contains(where, `"current"`):
+			fmt.Fprint(w, `{"_items": [{"state": "success", "branch": "master", "template": "wordpress"}]}`)
+		case strings.Contains(where, `"moved"`):
+			fmt.Fprint(w, `{"_items": [{"state": "success", "branch": "develop", "template": "drupal"}]}`)
+		case strings.Contains(where, `"broken"`):
+			fmt.Fprint(w, `{"_items": [{"state": "failed", "branch": "master", "template": "wordpress"}]}`)
+		default:
+			fmt.Fprint(w, `{"_items": []}`)
+		}
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	app := &Application{
+		AppID: "myapp",
+		Deploys: []Deploy{
+			{Name: "current", Branch: "master", Template: "wordpress"},
+			{Name: "moved", Branch: "master", Template: "wordpress"},
+			{Name: "broken", Branch: "master", Template: "wordpress"},
+			{Name: "new", Branch: "master", Template: "wordpress"},
+		},
+	}
+
+	drifted, err := r.CheckDrift(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, len(drifted), 3)
+	expect(t, drifted[0].Deploy.Name, "moved")
+	expect(t, strings.Join(drifted[0].Problems, ","), DriftBranchChanged+","+DriftTemplateChanged)
+	expect(t, drifted[1].Deploy.Name, "broken")
+	expect(t, strings.Join(drifted[1].Problems, ","), DriftFailing)
+	expect(t, drifted[2].Deploy.Name, "new")
+	expect(t, drifted[2].Build == nil, true)
+	expect(t, strings.Join(drifted[2].Problems, ","), DriftNeverBuilt)
+}
@@ -0,0 +1,77 @@
+package drudapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+)
+
+// relation tracks how a nested entity was returned by the api
+type relation int
+
+const (
+	relationUnknown   relation = iota // not decoded from the api
+	relationReference                 // only the _id was returned
+	relationEmbedded                  // the full entity was embedded
+)
+
+// decodeReference sets id and returns true if data is a bare _id rather than an object
+func decodeReference(data []byte, id *string) (bool, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || data[0] != '"' {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, id)
+}
+
+// Resolvable is a nested entity that may only be a reference to its _id
+type Resolvable interface {
+	EntityGetter
+	IsReference() bool
+}
+
+// Embed returns a copy of the request that asks eve to embed the given fields
+// e.g. r.Embed("client") when getting applications
+func (r *Request) Embed(fields ...string) *Request {
+	embedded := make(map[string]int)
+	for _, f := range fields {
+		embedded[f] = 1
+	}
+	jbytes, _ := json.Marshal(embedded)
+
+	q := *r
+	param := "embedded=" + url.QueryEscape(string(jbytes))
+	if q.Query != "" {
+		q.Query += "&" + param
+	} else {
+		q.Query = param
+	}
+	return &q
+}
+
+// Resolve gets each entity that is only a reference so it is fully populated
+func (r *Request) Resolve(entities ...Resolvable) error {
+	q := *r
+	q.Query = ""
+	for _, e := range entities {
+		if !e.IsReference() {
+			continue
+		}
+
+		err := q.Get(e)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResolveBuild resolves the build's client, application and container references
+func (r *Request) ResolveBuild(b *Build) error {
+	return r.Resolve(&b.Client, &b.Application, &b.Container)
+}
+
+// ResolveApplication resolves the application's client reference
+func (r *Request) ResolveApplication(a *Application) error {
+	return r.Resolve(&a.Client)
+}
@@ -0,0 +1,89 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildReferences(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/builds/b1":
+			if r.URL.Query().Get("embedded") == `{"application":1}` {
+				fmt.Fprint(w, `{"_id": "b1", "client": "c1", "application": {"_id": "a1", "app_id": "myapp", "name": "site"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"_id": "b1", "client": "c1", "application": "a1"}`)
+		case "/client/c1":
+			fmt.Fprint(w, `{"_id": "c1", "name": "acme"}`)
+		case "/application/a1":
+			fmt.Fprint(w, `{"_id": "a1", "app_id": "myapp", "name": "site"}`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	r := &Request{
+		Host: server.URL,
+	}
+
+	b := &Build{ID: "b1"}
+	err := r.Get(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, b.Client.IsReference(), true)
+	expect(t, b.Client.ID, "c1")
+	expect(t, b.Application.IsReference(), true)
+	expect(t, b.Application.ID, "a1")
+	expect(t, b.Container.IsReference(), false)
+	expect(t, b.Container.IsEmbedded(), false)
+
+	// references are sent back as their _id
+	var sent map[string]interface{}
+	json.Unmarshal(b.PatchJSON(), &sent)
+	expect(t, sent["client"], "c1")
+
+	err = r.ResolveBuild(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, b.Client.IsEmbedded(), true)
+	expect(t, b.Client.Name, "acme")
+	expect(t, b.Application.AppID, "myapp")
+
+	embedded := &Build{ID: "b1"}
+	err = r.Embed("application").Get(embedded)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, embedded.Application.IsEmbedded(), true)
+	expect(t, embedded.Application.Name, "site")
+	expect(t, embedded.Client.IsReference(), true)
+}
+
+func TestEmbedKeepsQuery(t *testing.T) {
+	r := &Request{Query: "page=2"}
+	expect(t, r.Embed("client", "application").Query, "page=2&embedded=%7B%22application%22%3A1%2C%22client%22%3A1%7D")
+	expect(t, r.Query, "page=2")
+}
+
+func TestClientRef(t *testing.T) {
+	app := Application{
+		AppID:  "myapp",
+		Name:   "site",
+		Client: ClientRef("c1"),
+	}
+	expect(t, app.Client.IsReference(), true)
+	expect(t, app.Validate("POST"), nil)
+
+	var sent map[string]interface{}
+	json.Unmarshal(app.JSON(), &sent)
+	expect(t, sent["client"], "c1")
+}
@@ -0,0 +1,94 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// jsonFields returns the json keys of the fields of struct type t
+func jsonFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = true
+	}
+	return fields
+}
+
+// unknownFields adds the fields of the json object in data that have no matching field in
+// v to extra. Eve meta fields such as _links and _status are skipped.
+func unknownFields(data []byte, v interface{}, extra map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	var raw map[string]json.RawMessage
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	known := jsonFields(reflect.Indirect(reflect.ValueOf(v)).Type())
+	for key, value := range raw {
+		if known[key] || strings.HasPrefix(key, "_") {
+			continue
+		}
+		if extra == nil {
+			extra = make(map[string]json.RawMessage)
+		}
+		extra[key] = value
+	}
+	return extra, nil
+}
+
+// withUnknownFields adds the fields in extra to the json object in jbytes without
+// overwriting any field already set
+func withUnknownFields(jbytes []byte, extra map[string]json.RawMessage) ([]byte, error) {
+	if len(extra) == 0 {
+		return jbytes, nil
+	}
+
+	var obj map[string]json.RawMessage
+	err := json.Unmarshal(jbytes, &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range extra {
+		if _, ok := obj[key]; !ok {
+			obj[key] = value
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// unmarshalWithExtra decodes data into plain, a pointer to an entity converted to a type
+// without an UnmarshalJSON method, and adds the fields the entity does not know about
+// to extra
+func unmarshalWithExtra(data []byte, plain interface{}, extra *map[string]json.RawMessage) error {
+	err := json.Unmarshal(data, plain)
+	if err != nil {
+		return err
+	}
+
+	*extra, err = unknownFields(data, plain, *extra)
+	return err
+}
+
+// marshalWithExtra encodes plain, an entity converted to a type without a MarshalJSON
+// method, along with the fields kept in extra
+func marshalWithExtra(plain interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	jbytes, err := json.Marshal(plain)
+	if err != nil {
+		return nil, err
+	}
+	return withUnknownFields(jbytes, extra)
+}
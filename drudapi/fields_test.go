@@ -0,0 +1,131 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"log"
+	"testing"
+)
+
+func TestUnknownFieldsRoundTrip(t *testing.T) {
+	expectedResp := `{
+    "app_id": "mumbojumbo",
+    "name": "killah",
+    "new_setting": {"enabled": true},
+    "client": {
+        "name": "somebiz",
+        "billing_id": 42
+    },
+    "deploys": [
+        {
+            "name": "default",
+            "branch": "master",
+            "php_version": "7.1"
+        }
+    ],
+    "_links": {"self": {"href": "application/mumbojumbo"}},
+    "_id": "d546fh5ert456",
+    "_etag": "34b4d4c312a2d5cb916fef5330b2a14f53acac4b"
+}`
+	server := getTestServer(200, expectedResp)
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	a := &Application{
+		AppID: "mumbojumbo",
+	}
+
+	err := r.Get(a)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, len(a.Extra), 1)
+	expect(t, string(a.Extra["new_setting"]), `{"enabled": true}`)
+	expect(t, string(a.Client.Extra["billing_id"]), "42")
+	expect(t, string(a.Deploys[0].Extra["php_version"]), `"7.1"`)
+
+	a.Deploys[0].Branch = "develop"
+
+	var patch map[string]interface{}
+	err = json.Unmarshal(a.PatchJSON(), &patch)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, patch["new_setting"].(map[string]interface{})["enabled"], true)
+	expect(t, patch["client"].(map[string]interface{})["billing_id"], float64(42))
+	deploy := patch["deploys"].([]interface{})[0].(map[string]interface{})
+	expect(t, deploy["php_version"], "7.1")
+	expect(t, deploy["branch"], "develop")
+	_, ok := patch["_links"]
+	expect(t, ok, false)
+	_, ok = patch["app_id"]
+	expect(t, ok, false)
+}
+
+func TestUnknownFieldsSurvivePatchResponse(t *testing.T) {
+	c := &Client{}
+	err := c.Unmarshal([]byte(`{"name": "somebiz", "billing_id": 42}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// eve only returns meta fields in response to a patch
+	err = c.Unmarshal([]byte(`{"_id": "1234", "_etag": "newetag", "_status": "OK"}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, c.Etag, "newetag")
+	expect(t, string(c.Extra["billing_id"]), "42")
+}
+
+// extraThing uses the Extra helpers the way entities do
+type extraThing struct {
+	Name  string                     `json:"name"`
+	Count int                        `json:"count,omitempty"`
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+func (e *extraThing) UnmarshalJSON(data []byte) error {
+	type plain extraThing
+	return unmarshalWithExtra(data, (*plain)(e), &e.Extra)
+}
+
+func (e extraThing) MarshalJSON() ([]byte, error) {
+	type plain extraThing
+	return marshalWithExtra(plain(e), e.Extra)
+}
+
+func TestExtraHelpers(t *testing.T) {
+	var e extraThing
+	err := json.Unmarshal([]byte(`{"name": "one", "count": 2, "color": "red", "_links": {}}`), &e)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, e.Name, "one")
+	expect(t, e.Count, 2)
+	expect(t, len(e.Extra), 1)
+	expect(t, string(e.Extra["color"]), `"red"`)
+
+	// declared fields win over a stale copy kept in Extra
+	e.Name = "two"
+	e.Extra["name"] = json.RawMessage(`"stale"`)
+	jbytes, err := json.Marshal(e)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, string(jbytes), `{"color":"red","count":2,"name":"two"}`)
+
+	err = json.Unmarshal([]byte(`{"name": 1}`), &e)
+	refute(t, err, nil)
+
+	jbytes, err = json.Marshal(extraThing{Name: "plain"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, string(jbytes), `{"name":"plain"}`)
+}
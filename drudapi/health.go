@@ -0,0 +1,95 @@
+package drudapi
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/drud/drud-go/utils/network"
+	"github.com/gosuri/uitable"
+)
+
+// DeployHealth is the result of probing a deploy's url
+type DeployHealth struct {
+	Deploy     string
+	URL        string
+	Healthy    bool
+	StatusCode int
+	Latency    time.Duration
+	TLSExpiry  time.Time // zero for http deploys
+	TLSError   error     // set when the certificate did not verify
+	Err        error
+}
+
+// CheckHealth probes the deploy's url with its basic auth credentials and reports
+// whether it responds with a 200 within timeout. https deploys must present a
+// certificate trusted by roots, the system roots are used when nil. lookup reads the
+// basic auth of deploys that refer to a secret, e.g. VaultSecrets(vault).
+func (d Deploy) CheckHealth(timeout time.Duration, roots *x509.CertPool, lookup SecretLookup) DeployHealth {
+	h := DeployHealth{
+		Deploy: d.Name,
+		URL:    d.SiteURL(),
+	}
+	if h.URL == "" {
+		h.Err = fmt.Errorf("Deploy %s has no url", d.Name)
+		return h
+	}
+
+	user, pass, err := d.BasicAuthCredentials(lookup)
+	if err != nil {
+		h.Err = err
+		return h
+	}
+
+	o := network.NewHTTPOptions(h.URL)
+	o.Username = user
+	o.Password = pass
+	o.RootCAs = roots
+	o.Timeout = timeout / time.Second
+	if o.Timeout == 0 {
+		o.Timeout = 1
+	}
+
+	result, err := network.CheckHTTPStatus(o)
+	if result != nil {
+		h.StatusCode = result.StatusCode
+		h.Latency = result.Latency
+		h.TLSExpiry = result.TLSExpiry
+		h.TLSError = result.TLSError
+	}
+	h.Err = err
+	h.Healthy = err == nil
+	return h
+}
+
+// CheckHealth probes every deploy of the application
+func (a *Application) CheckHealth(timeout time.Duration, roots *x509.CertPool, lookup SecretLookup) []DeployHealth {
+	var report []DeployHealth
+	for _, d := range a.Deploys {
+		report = append(report, d.CheckHealth(timeout, roots, lookup))
+	}
+	return report
+}
+
+// DescribeHealth pretty prints a health report
+func DescribeHealth(report []DeployHealth) {
+	table := uitable.New()
+	table.MaxColWidth = 50
+	table.AddRow("DEPLOY", "URL", "HEALTHY", "STATUS", "LATENCY", "TLS EXPIRES")
+	for _, h := range report {
+		var healthy, expires, status string
+		if h.Healthy {
+			healthy = "✓"
+		}
+		if !h.TLSExpiry.IsZero() {
+			expires = h.TLSExpiry.Format("2006-01-02")
+		}
+		if h.StatusCode != 0 {
+			status = fmt.Sprint(h.StatusCode)
+		} else if h.Err != nil {
+			status = h.Err.Error()
+		}
+		table.AddRow(h.Deploy, h.URL, healthy, status, h.Latency.String(), expires)
+	}
+	fmt.Println(table)
+}
@@ -0,0 +1,101 @@
+package drudapi
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplicationCheckHealth(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if ok && (user != "drud" || pass != "secret") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	})
+
+	site := httptest.NewServer(handler)
+	defer site.Close()
+	secure := httptest.NewTLSServer(handler)
+	defer secure.Close()
+
+	app := &Application{
+		Deploys: []Deploy{
+			{Name: "default", Protocol: "http", Url: strings.TrimPrefix(site.URL, "http://")},
+			{Name: "secure", Protocol: "https", Url: strings.TrimPrefix(secure.URL, "https://"), BasicAuthUser: "drud", BasicAuthPass: "secret"},
+			{Name: "badauth", Protocol: "http", Url: strings.TrimPrefix(site.URL, "http://"), BasicAuthUser: "drud", BasicAuthPass: "wrong"},
+			{Name: "nourl"},
+		},
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(secure.Certificate())
+	report := app.CheckHealth(5*time.Second, roots, nil)
+	expect(t, len(report), 4)
+
+	expect(t, report[0].Healthy, true)
+	expect(t, report[0].StatusCode, 200)
+	expect(t, report[0].TLSExpiry.IsZero(), true)
+	expect(t, report[0].Latency > 0, true)
+
+	expect(t, report[1].Healthy, true)
+	expect(t, report[1].URL, secure.URL)
+	expect(t, report[1].TLSExpiry.After(time.Now()), true)
+
+	expect(t, report[2].Healthy, false)
+	expect(t, report[2].StatusCode, http.StatusUnauthorized)
+
+	expect(t, report[3].Healthy, false)
+	refute(t, report[3].Err, nil)
+}
+
+func TestCheckHealthUntrustedCertificate(t *testing.T) {
+	var requests int
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, "ok")
+	}))
+	secure.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+	defer secure.Close()
+
+	d := Deploy{Name: "secure", Protocol: "https", Url: strings.TrimPrefix(secure.URL, "https://"), BasicAuthUser: "drud", BasicAuthPass: "secret"}
+	h := d.CheckHealth(5*time.Second, nil, nil)
+
+	expect(t, h.Healthy, false)
+	refute(t, h.Err, nil)
+	refute(t, h.TLSError, nil)
+	expect(t, h.StatusCode, 0)
+	expect(t, h.TLSExpiry.After(time.Now()), true)
+	// credentials are never sent to a server that failed verification
+	expect(t, requests, 0)
+}
+
+func TestCheckHealthReadsBasicAuthSecret(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "drud" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer site.Close()
+
+	vault := fakeVault{"secret/myapp/default/basicauth": {"username": "drud", "password": "secret"}}
+	d := Deploy{Name: "default", Protocol: "http", Url: strings.TrimPrefix(site.URL, "http://"), BasicAuthSecret: "secret/myapp/default/basicauth"}
+
+	h := d.CheckHealth(5*time.Second, nil, vault.lookup)
+	expect(t, h.Healthy, true)
+
+	h = d.CheckHealth(5*time.Second, nil, nil)
+	expect(t, h.Healthy, false)
+	refute(t, h.Err, nil)
+}
@@ -0,0 +1,259 @@
+package drudapi
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gosuri/uitable"
+)
+
+// Verification states of a custom hostname
+const (
+	HostnamePending  = "pending"
+	HostnameVerified = "verified"
+	HostnameFailed   = "failed"
+)
+
+// HostnameTXTPrefix is prepended to a custom hostname to find its TXT ownership record
+var HostnameTXTPrefix = "_drud-verify."
+
+// CustomHostname is a hostname a customer points at a deploy
+type CustomHostname struct {
+	Name    string `json:"name"`
+	Token   string `json:"token,omitempty"`   // expected in the TXT ownership record
+	Status  string `json:"status,omitempty"`  // pending, verified or failed
+	Method  string `json:"method,omitempty"`  // the record type that verified it, cname, a or txt
+	Checked string `json:"checked,omitempty"` // when it was last verified
+	Error   string `json:"error,omitempty"`   // why verification failed
+}
+
+// Resolver looks up the dns records used to verify custom hostnames
+type Resolver interface {
+	LookupCNAME(host string) (string, error)
+	LookupHost(host string) ([]string, error)
+	LookupTXT(host string) ([]string, error)
+}
+
+// NetResolver resolves hostnames with the system resolver
+type NetResolver struct{}
+
+// LookupCNAME ...
+func (NetResolver) LookupCNAME(host string) (string, error) {
+	return net.LookupCNAME(host)
+}
+
+// LookupHost ...
+func (NetResolver) LookupHost(host string) ([]string, error) {
+	return net.LookupHost(host)
+}
+
+// LookupTXT ...
+func (NetResolver) LookupTXT(host string) ([]string, error) {
+	return net.LookupTXT(host)
+}
+
+// FakeResolver answers lookups from fixed records so verification can run offline.
+// Hosts missing from a map are treated as having no records of that type.
+type FakeResolver struct {
+	CNAME map[string]string
+	Hosts map[string][]string
+	TXT   map[string][]string
+}
+
+func notFound(host string) error {
+	return &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// LookupCNAME ...
+func (f FakeResolver) LookupCNAME(host string) (string, error) {
+	if cname, ok := f.CNAME[host]; ok {
+		return cname, nil
+	}
+	return "", notFound(host)
+}
+
+// LookupHost ...
+func (f FakeResolver) LookupHost(host string) ([]string, error) {
+	if addrs, ok := f.Hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, notFound(host)
+}
+
+// LookupTXT ...
+func (f FakeResolver) LookupTXT(host string) ([]string, error) {
+	if records, ok := f.TXT[host]; ok {
+		return records, nil
+	}
+	return nil, notFound(host)
+}
+
+func sameHost(a string, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+// VerifyHostname checks h is owned through its TXT token or points at target through a
+// CNAME or A records, and records the result on h
+func VerifyHostname(res Resolver, target string, h *CustomHostname) {
+	h.Checked = time.Now().UTC().Format(time.RFC1123)
+	h.Status = HostnameVerified
+	h.Error = ""
+
+	records, _ := res.LookupTXT(HostnameTXTPrefix + h.Name)
+	for _, record := range records {
+		if h.Token != "" && record == h.Token {
+			h.Method = "txt"
+			return
+		}
+	}
+
+	cname, err := res.LookupCNAME(h.Name)
+	if err == nil && sameHost(cname, target) {
+		h.Method = "cname"
+		return
+	}
+
+	addrs, err := res.LookupHost(h.Name)
+	if err != nil {
+		h.Method = ""
+		h.Status = HostnameFailed
+		h.Error = err.Error()
+		return
+	}
+
+	targetAddrs, err := res.LookupHost(target)
+	if err != nil {
+		h.Method = ""
+		h.Status = HostnameFailed
+		h.Error = fmt.Sprintf("Could not resolve target %s: %s", target, err)
+		return
+	}
+
+	for _, addr := range addrs {
+		for _, targetAddr := range targetAddrs {
+			if addr == targetAddr {
+				h.Method = "a"
+				return
+			}
+		}
+	}
+
+	h.Method = ""
+	h.Status = HostnameFailed
+	h.Error = fmt.Sprintf("%s resolves to %s, expected %s (%s)", h.Name, strings.Join(addrs, ", "), target, strings.Join(targetAddrs, ", "))
+}
+
+// hostnameTarget is the address custom hostnames of d should point at
+func hostnameTarget(d *Deploy) (string, error) {
+	if d.Url != "" {
+		return d.Url, nil
+	}
+	if d.Hostname != "" {
+		return d.Hostname, nil
+	}
+	return "", fmt.Errorf("Deploy %s has no url for hostnames to point at", d.Name)
+}
+
+// AddHostname adds a pending custom hostname to a deploy of app. The returned hostname's
+// token can be published in a TXT record at HostnameTXTPrefix + name to prove ownership.
+func (r *Request) AddHostname(app *Application, deployName string, name string) (*CustomHostname, error) {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if !IsHostname(name) {
+		return nil, fmt.Errorf("%q is not a valid hostname", name)
+	}
+
+	d := app.GetDeploy(deployName)
+	if d == nil {
+		return nil, fmt.Errorf("No deploy found by name %s", deployName)
+	}
+	for _, existing := range app.Deploys {
+		if existing.GetHostname(name) != nil {
+			return nil, fmt.Errorf("Hostname %s is already used by deploy %s", name, existing.Name)
+		}
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	d.Hostnames = append(d.Hostnames, CustomHostname{
+		Name:   name,
+		Token:  token,
+		Status: HostnamePending,
+	})
+	err = r.Patch(app)
+	if err != nil {
+		d.Hostnames = d.Hostnames[:len(d.Hostnames)-1]
+		return nil, err
+	}
+	return &d.Hostnames[len(d.Hostnames)-1], nil
+}
+
+// RemoveHostname removes a custom hostname from a deploy of app
+func (r *Request) RemoveHostname(app *Application, deployName string, name string) error {
+	d := app.GetDeploy(deployName)
+	if d == nil {
+		return fmt.Errorf("No deploy found by name %s", deployName)
+	}
+
+	hostnames := d.Hostnames
+	for i, h := range hostnames {
+		if sameHost(h.Name, name) {
+			d.Hostnames = append(append([]CustomHostname{}, hostnames[:i]...), hostnames[i+1:]...)
+			err := r.Patch(app)
+			if err != nil {
+				d.Hostnames = hostnames
+			}
+			return err
+		}
+	}
+	return fmt.Errorf("No hostname %s on deploy %s", name, deployName)
+}
+
+// VerifyHostnames verifies every custom hostname of a deploy with res and saves the
+// results to the api
+func (r *Request) VerifyHostnames(app *Application, deployName string, res Resolver) ([]CustomHostname, error) {
+	d := app.GetDeploy(deployName)
+	if d == nil {
+		return nil, fmt.Errorf("No deploy found by name %s", deployName)
+	}
+	if len(d.Hostnames) == 0 {
+		return nil, nil
+	}
+
+	target, err := hostnameTarget(d)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range d.Hostnames {
+		VerifyHostname(res, target, &d.Hostnames[i])
+	}
+
+	err = r.Patch(app)
+	if err != nil {
+		return nil, err
+	}
+	return d.Hostnames, nil
+}
+
+// DescribeHostnames pretty prints the custom hostnames of a deploy and how to verify them
+func DescribeHostnames(d *Deploy) {
+	fmt.Printf("%v custom %v for %s.\n\n", len(d.Hostnames), FormatPlural(len(d.Hostnames), "hostname", "hostnames"), d.Name)
+
+	target, _ := hostnameTarget(d)
+	table := uitable.New()
+	table.MaxColWidth = 60
+	table.AddRow("HOSTNAME", "STATUS", "METHOD", "CHECKED", "DETAIL")
+	for _, h := range d.Hostnames {
+		detail := h.Error
+		if h.Status != HostnameVerified && detail == "" {
+			detail = fmt.Sprintf("CNAME %s or TXT %s%s %s", target, HostnameTXTPrefix, h.Name, h.Token)
+		}
+		table.AddRow(h.Name, h.Status, h.Method, h.Checked, detail)
+	}
+	fmt.Println(table)
+}
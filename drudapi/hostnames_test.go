@@ -0,0 +1,118 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerifyHostname(t *testing.T) {
+	res := FakeResolver{
+		CNAME: map[string]string{
+			"www.example.com":   "myapp-production.drud.io.",
+			"wrong.example.com": "elsewhere.example.net.",
+		},
+		Hosts: map[string][]string{
+			"myapp-production.drud.io": {"10.0.0.1", "10.0.0.2"},
+			"example.com":              {"10.0.0.2"},
+			"wrong.example.com":        {"192.168.1.1"},
+		},
+		TXT: map[string][]string{
+			"_drud-verify.shop.example.com": {"v=spf1 -all", "abc123"},
+		},
+	}
+	target := "myapp-production.drud.io"
+
+	cases := []struct {
+		host   CustomHostname
+		status string
+		method string
+	}{
+		{CustomHostname{Name: "www.example.com"}, HostnameVerified, "cname"},
+		{CustomHostname{Name: "example.com"}, HostnameVerified, "a"},
+		{CustomHostname{Name: "shop.example.com", Token: "abc123"}, HostnameVerified, "txt"},
+		{CustomHostname{Name: "shop.example.com", Token: "other"}, HostnameFailed, ""},
+		{CustomHostname{Name: "wrong.example.com"}, HostnameFailed, ""},
+	}
+
+	for _, c := range cases {
+		h := c.host
+		VerifyHostname(res, target, &h)
+		expect(t, h.Status, c.status)
+		expect(t, h.Method, c.method)
+		refute(t, h.Checked, "")
+		if c.status == HostnameFailed {
+			refute(t, h.Error, "")
+		}
+	}
+}
+
+func TestHostnameWorkflow(t *testing.T) {
+	var saved Application
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.URL.Path, "/application/myapp")
+		expect(t, r.Method, "PATCH")
+		body, _ := ioutil.ReadAll(r.Body)
+		saved = Application{}
+		json.Unmarshal(body, &saved)
+		fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	app := &Application{
+		AppID: "myapp",
+		Etag:  "etag1",
+		Deploys: []Deploy{
+			{Name: "production", Url: "myapp-production.drud.io"},
+			{Name: "staging", Url: "myapp-staging.drud.io"},
+		},
+	}
+
+	h, err := r.AddHostname(app, "production", "WWW.Example.com.")
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, h.Name, "www.example.com")
+	expect(t, h.Status, HostnamePending)
+	expect(t, len(h.Token), TokenLength*2)
+	expect(t, len(saved.Deploys[0].Hostnames), 1)
+
+	_, err = r.AddHostname(app, "staging", "www.example.com")
+	refute(t, err, nil)
+	_, err = r.AddHostname(app, "production", "not_a_host")
+	refute(t, err, nil)
+
+	_, err = r.AddHostname(app, "production", "shop.example.com")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	res := FakeResolver{
+		CNAME: map[string]string{"www.example.com": "myapp-production.drud.io."},
+	}
+	hostnames, err := r.VerifyHostnames(app, "production", res)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, hostnames[0].Status, HostnameVerified)
+	expect(t, hostnames[1].Status, HostnameFailed)
+	expect(t, saved.Deploys[0].Hostnames[1].Status, HostnameFailed)
+
+	err = r.RemoveHostname(app, "production", "www.example.com")
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(app.Deploys[0].Hostnames), 1)
+	expect(t, saved.Deploys[0].Hostnames[0].Name, "shop.example.com")
+
+	err = r.RemoveHostname(app, "production", "www.example.com")
+	refute(t, err, nil)
+}
@@ -0,0 +1,258 @@
+package drudapi
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// InventoryRow describes one deploy of an application and its latest build
+type InventoryRow struct {
+	Client      string
+	Application string
+	AppID       string
+	Deploy      string
+	Branch      string
+	Template    string
+	Protocol    string
+	URL         string
+	BuildState  string    // empty when the deploy has never been built
+	BuildTime   time.Time // zero when the deploy has never been built
+}
+
+// BuildAge returns how long ago the row's latest build was created relative to now
+func (row InventoryRow) BuildAge(now time.Time) string {
+	if row.BuildTime.IsZero() {
+		return ""
+	}
+	return FormatAge(now.Sub(row.BuildTime))
+}
+
+// InventoryGroup holds the rows of a single client
+type InventoryGroup struct {
+	Client string
+	Rows   []InventoryRow
+}
+
+// Inventory is a report of every client, application and deploy
+type Inventory struct {
+	Generated time.Time
+	Groups    []InventoryGroup
+}
+
+// GatherInventory gets every client, application and the latest build of each deploy.
+// Builds are fetched with at most workers requests in flight.
+func (r *Request) GatherInventory(workers int) (*Inventory, error) {
+	clients, err := r.AllClients()
+	if err != nil {
+		return nil, err
+	}
+	apps, err := r.AllApplications()
+	if err != nil {
+		return nil, err
+	}
+
+	names := ClientNamesByID(clients)
+	var rows []InventoryRow
+	var rowApps []*Application
+	for i := range apps {
+		app := &apps[i]
+		for _, d := range app.Deploys {
+			rows = append(rows, InventoryRow{
+				Client:      app.ClientName(names),
+				Application: app.Name,
+				AppID:       app.AppID,
+				Deploy:      d.Name,
+				Branch:      d.Branch,
+				Template:    d.Template,
+				Protocol:    d.Protocol,
+				URL:         d.SiteURL(),
+			})
+			rowApps = append(rowApps, app)
+		}
+	}
+
+	results := r.batch(len(rows), workers, func(q *Request, i int) error {
+		b, err := q.LatestBuild(rowApps[i], rows[i].Deploy, "")
+		if err != nil || b == nil {
+			return err
+		}
+		rows[i].BuildState = b.State
+		rows[i].BuildTime, _ = ParseTime(b.Created)
+		return nil
+	})
+	if failed := BatchErrors(results); len(failed) > 0 {
+		row := rows[failed[0].Index]
+		return nil, fmt.Errorf("Could not get builds for %s/%s: %s", row.AppID, row.Deploy, failed[0].Err)
+	}
+
+	groups := make(map[string]*InventoryGroup)
+	for _, c := range clients {
+		groups[c.Name] = &InventoryGroup{Client: c.Name}
+	}
+	for _, row := range rows {
+		g, ok := groups[row.Client]
+		if !ok {
+			g = &InventoryGroup{Client: row.Client}
+			groups[row.Client] = g
+		}
+		g.Rows = append(g.Rows, row)
+	}
+
+	inv := &Inventory{
+		Generated: time.Now(),
+	}
+	for _, g := range groups {
+		sort.Slice(g.Rows, func(i, j int) bool {
+			if g.Rows[i].Application != g.Rows[j].Application {
+				return g.Rows[i].Application < g.Rows[j].Application
+			}
+			return g.Rows[i].Deploy < g.Rows[j].Deploy
+		})
+		inv.Groups = append(inv.Groups, *g)
+	}
+	sort.Slice(inv.Groups, func(i, j int) bool {
+		return inv.Groups[i].Client < inv.Groups[j].Client
+	})
+	return inv, nil
+}
+
+// inventoryColumns are the column headings shared by every inventory format
+var inventoryColumns = []string{"CLIENT", "APPLICATION", "DEPLOY", "BRANCH", "TEMPLATE", "PROTOCOL", "URL", "LAST BUILD", "AGE"}
+
+// values returns the row's columns in the order of inventoryColumns
+func (row InventoryRow) values(now time.Time) []string {
+	return []string{
+		row.Client,
+		row.Application,
+		row.Deploy,
+		row.Branch,
+		row.Template,
+		row.Protocol,
+		row.URL,
+		row.BuildState,
+		row.BuildAge(now),
+	}
+}
+
+// WriteCSV writes the inventory as csv with one line per deploy
+func (inv *Inventory) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(inventoryColumns)
+	if err != nil {
+		return err
+	}
+
+	for _, g := range inv.Groups {
+		for _, row := range g.Rows {
+			err = cw.Write(row.values(inv.Generated))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteMarkdown writes the inventory as a markdown document with a table per client
+func (inv *Inventory) WriteMarkdown(w io.Writer) error {
+	escape := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	_, err := fmt.Fprintf(w, "# Site inventory\n\nGenerated %s\n", inv.Generated.Format(time.RFC1123))
+	if err != nil {
+		return err
+	}
+
+	columns := inventoryColumns[1:]
+	for _, g := range inv.Groups {
+		fmt.Fprintf(w, "\n## %s\n\n", escape.Replace(g.Client))
+		if len(g.Rows) == 0 {
+			fmt.Fprintln(w, "No applications.")
+			continue
+		}
+
+		fmt.Fprintf(w, "| %s |\n", strings.Join(columns, " | "))
+		fmt.Fprintf(w, "|%s\n", strings.Repeat(" --- |", len(columns)))
+		for _, row := range g.Rows {
+			var cells []string
+			for _, v := range row.values(inv.Generated)[1:] {
+				cells = append(cells, escape.Replace(v))
+			}
+			_, err = fmt.Fprintf(w, "| %s |\n", strings.Join(cells, " | "))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var inventoryHTML = template.Must(template.New("inventory").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Site inventory</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+th { background: #eee; }
+.failed { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Site inventory</h1>
+<p>Generated {{.Generated}}</p>
+{{range .Groups}}
+<h2>{{.Client}}</h2>
+{{if .Rows}}
+<table>
+<tr>{{range $.Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr{{if .Failed}} class="failed"{{end}}>{{range .Values}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{else}}
+<p>No applications.</p>
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML writes the inventory as a standalone html page with a table per client
+func (inv *Inventory) WriteHTML(w io.Writer) error {
+	type htmlRow struct {
+		Failed bool
+		Values []string
+	}
+	type htmlGroup struct {
+		Client string
+		Rows   []htmlRow
+	}
+
+	data := struct {
+		Generated string
+		Columns   []string
+		Groups    []htmlGroup
+	}{
+		Generated: inv.Generated.Format(time.RFC1123),
+		Columns:   inventoryColumns[1:],
+	}
+	for _, g := range inv.Groups {
+		hg := htmlGroup{Client: g.Client}
+		for _, row := range g.Rows {
+			hg.Rows = append(hg.Rows, htmlRow{
+				Failed: row.BuildState == BuildStateFailed,
+				Values: row.values(inv.Generated)[1:],
+			})
+		}
+		data.Groups = append(data.Groups, hg)
+	}
+
+	return inventoryHTML.Execute(w, data)
+}
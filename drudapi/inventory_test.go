@@ -0,0 +1,108 @@
+package drudapi
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func inventoryTestServer(t *testing.T) *httptest.Server {
+	built := time.Now().Add(-72 * time.Hour).UTC().Format(time.RFC1123)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/client":
+			fmt.Fprint(w, `{"_items": [{"_id": "c1", "name": "acme"}, {"_id": "c2", "name": "idle"}], "_meta": {"total": 2}}`)
+		case "/application":
+			if r.URL.Query().Get("page") == "1" {
+				fmt.Fprint(w, `{"_items": [{"_id": "a1", "app_id": "acme-site", "name": "site", "client": "c1",
+					"deploys": [{"name": "production", "branch": "master", "template": "wordpress", "protocol": "https", "url": "acme.com"},
+					            {"name": "staging", "branch": "develop", "template": "wordpress", "protocol": "http", "url": "staging.acme.com"}]}],
+					"_meta": {"total": 2}}`)
+				return
+			}
+			fmt.Fprint(w, `{"_items": [{"_id": "a2", "app_id": "acme-blog", "name": "blog|news", "client": "c1",
+				"deploys": [{"name": "default", "branch": "master", "template": "drupal"}]}], "_meta": {"total": 2}}`)
+		case "/builds":
+			where := r.URL.Query().Get("where")
+			switch {
+			case strings.Contains(where, `"production"`):
+				fmt.Fprintf(w, `{"_items": [{"state": "success", "_created": "%s"}]}`, built)
+			case strings.Contains(where, `"staging"`):
+				fmt.Fprintf(w, `{"_items": [{"state": "failed", "_created": "%s"}]}`, built)
+			default:
+				fmt.Fprint(w, `{"_items": []}`)
+			}
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestGatherInventory(t *testing.T) {
+	server := inventoryTestServer(t)
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	inv, err := r.GatherInventory(2)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, len(inv.Groups), 2)
+	expect(t, inv.Groups[0].Client, "acme")
+	expect(t, len(inv.Groups[0].Rows), 3)
+	expect(t, inv.Groups[1].Client, "idle")
+	expect(t, len(inv.Groups[1].Rows), 0)
+
+	rows := inv.Groups[0].Rows
+	expect(t, rows[0].Application, "blog|news")
+	expect(t, rows[0].BuildState, "")
+	expect(t, rows[1].Deploy, "production")
+	expect(t, rows[1].BuildState, "success")
+	expect(t, rows[1].URL, "https://acme.com")
+	expect(t, rows[1].BuildAge(inv.Generated), "3d")
+	expect(t, rows[2].BuildState, "failed")
+
+	var buf bytes.Buffer
+	err = inv.WriteCSV(&buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(records), 4)
+	expect(t, records[2][2], "production")
+	expect(t, records[2][8], "3d")
+
+	buf.Reset()
+	err = inv.WriteMarkdown(&buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	md := buf.String()
+	expect(t, strings.Contains(md, "## acme"), true)
+	expect(t, strings.Contains(md, `| blog\|news | default |`), true)
+	expect(t, strings.Contains(md, "## idle\n\nNo applications."), true)
+
+	buf.Reset()
+	err = inv.WriteHTML(&buf)
+	if err != nil {
+		log.Fatal(err)
+	}
+	html := buf.String()
+	expect(t, strings.HasPrefix(html, "<!DOCTYPE html>"), true)
+	expect(t, strings.Contains(html, `<tr class="failed">`), true)
+	expect(t, strings.Contains(html, "<h2>idle</h2>"), true)
+}
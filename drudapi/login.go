@@ -0,0 +1,222 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// OTPHeader is the header used to send a 2fa token along with a login request
+const OTPHeader = "X-Drud-OTP"
+
+// storedToken is the on disk representation of a host's credentials
+type storedToken struct {
+	Username string `json:"username"`
+	Token    string `json:"auth_token"`
+}
+
+// TokenStore persists auth tokens per api host in a json file only readable by the user
+type TokenStore struct {
+	Path string
+	lock sync.Mutex
+}
+
+// DefaultTokenStore returns a store at drud/credentials.json in the user's config dir
+func DefaultTokenStore() (*TokenStore, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenStore{
+		Path: filepath.Join(dir, "drud", "credentials.json"),
+	}, nil
+}
+
+// read returns all tokens in the store
+func (s *TokenStore) read() (map[string]storedToken, error) {
+	tokens := make(map[string]storedToken)
+
+	data, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &tokens)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// write replaces the contents of the store with tokens
+func (s *TokenStore) write(tokens map[string]storedToken) error {
+	err := os.MkdirAll(filepath.Dir(s.Path), 0700)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(s.Path, data, 0600)
+	if err != nil {
+		return err
+	}
+	// WriteFile does not change the mode of an existing file
+	return os.Chmod(s.Path, 0600)
+}
+
+// Load returns the credentials stored for host or nil if there are none
+func (s *TokenStore) Load(host string) (*Credentials, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	t, ok := tokens[host]
+	if !ok {
+		return nil, nil
+	}
+
+	return &Credentials{
+		Username: t.Username,
+		Token:    t.Token,
+	}, nil
+}
+
+// Save stores the username and token of creds for host
+func (s *TokenStore) Save(host string, creds *Credentials) error {
+	if creds == nil || creds.Token == "" {
+		return errors.New("no auth_token to save")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	tokens[host] = storedToken{
+		Username: creds.Username,
+		Token:    creds.Token,
+	}
+	return s.write(tokens)
+}
+
+// Remove deletes the credentials stored for host
+func (s *TokenStore) Remove(host string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := tokens[host]; !ok {
+		return nil
+	}
+
+	delete(tokens, host)
+	return s.write(tokens)
+}
+
+// Login exchanges username and password (and otp when it is not empty) for the user's
+// auth_token. The token is set as the request's Auth and saved to the request's Store.
+func (r *Request) Login(username string, password string, otp string) error {
+	login := &Request{
+		Host: r.Host,
+		Auth: &Credentials{
+			Username: username,
+			Password: password,
+		},
+		Headers: make(map[string]string),
+	}
+	for header, value := range r.Headers {
+		login.Headers[header] = value
+	}
+	if otp != "" {
+		login.Headers[OTPHeader] = otp
+	}
+
+	u := &User{
+		Username: username,
+	}
+	err := login.Get(u)
+	if err != nil {
+		return err
+	}
+
+	if u.Token == "" {
+		return errors.New("no auth_token returned for " + username)
+	}
+
+	r.Auth = &Credentials{
+		Username: u.Username,
+		Token:    u.Token,
+	}
+
+	if r.Store != nil {
+		return r.Store.Save(r.Host, r.Auth)
+	}
+	return nil
+}
+
+// PromptLogin logs in with the username and password returned by credentials and,
+// when otp is not nil, the 2fa token it returns. It is meant to be used with the
+// prompts in the secrets package e.g. r.PromptLogin(secrets.GetCredentials, secrets.GetOTP)
+func (r *Request) PromptLogin(credentials func() (string, string), otp func() string) error {
+	username, password := credentials()
+
+	var code string
+	if otp != nil {
+		code = otp()
+	}
+
+	return r.Login(username, password, code)
+}
+
+// Logout clears the request's Auth and removes its token from the Store
+func (r *Request) Logout() error {
+	r.Auth = nil
+
+	if r.Store != nil {
+		return r.Store.Remove(r.Host)
+	}
+	return nil
+}
+
+// WhoAmI returns the user the request is authenticated as
+func (r *Request) WhoAmI() (*User, error) {
+	creds, err := r.credentials()
+	if err != nil {
+		return nil, err
+	}
+
+	if creds == nil || creds.Username == "" {
+		return nil, errors.New("Not logged in")
+	}
+
+	u := &User{
+		Username: creds.Username,
+	}
+	err = r.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
@@ -0,0 +1,163 @@
+package drudapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func tempTokenStore() (*TokenStore, func()) {
+	dir, err := ioutil.TempDir("", "drudapi")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	store := &TokenStore{
+		Path: filepath.Join(dir, "drud", "credentials.json"),
+	}
+	return store, func() { os.RemoveAll(dir) }
+}
+
+func loginTestServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.URL.Path, "/users/fred")
+
+		if r.Header.Get("Authorization") == "Bearer sometoken" {
+			fmt.Fprint(w, `{"username": "fred", "_id": "1234"}`)
+			return
+		}
+
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "fred" || password != "hunter2" || r.Header.Get(OTPHeader) != "123456" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"username": "fred", "auth_token": "sometoken", "_id": "1234"}`)
+	}))
+}
+
+func TestLogin(t *testing.T) {
+	store, cleanup := tempTokenStore()
+	defer cleanup()
+
+	server := loginTestServer(t)
+	defer server.Close()
+
+	r := &Request{
+		Host:  server.URL,
+		Store: store,
+	}
+
+	err := r.Login("fred", "wrong", "123456")
+	refute(t, err, nil)
+
+	err = r.Login("fred", "hunter2", "123456")
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, r.Auth.Token, "sometoken")
+
+	info, err := os.Stat(store.Path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, info.Mode().Perm(), os.FileMode(0600))
+
+	// a new request for the same host should pick up the stored token
+	r2 := &Request{
+		Host:  server.URL,
+		Store: store,
+	}
+	u, err := r2.WhoAmI()
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, u.Username, "fred")
+	expect(t, u.ID, "1234")
+
+	err = r2.Logout()
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, r2.Auth == nil, true)
+
+	creds, err := store.Load(server.URL)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, creds == nil, true)
+
+	_, err = r2.WhoAmI()
+	refute(t, err, nil)
+}
+
+func TestTokenStorePerHost(t *testing.T) {
+	store, cleanup := tempTokenStore()
+	defer cleanup()
+
+	err := store.Save("https://one.example.com", &Credentials{Username: "fred", Token: "one"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = store.Save("https://two.example.com", &Credentials{Username: "fred", Token: "two"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	creds, err := store.Load("https://one.example.com")
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, creds.Token, "one")
+
+	creds, err = store.Load("https://two.example.com")
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, creds.Token, "two")
+
+	err = store.Save("https://two.example.com", &Credentials{Username: "fred"})
+	refute(t, err, nil)
+}
+
+// TestSharedRequest checks a request that loads its credentials from a store can be
+// used from several goroutines, run it with -race
+func TestSharedRequest(t *testing.T) {
+	store, cleanup := tempTokenStore()
+	defer cleanup()
+
+	server := loginTestServer(t)
+	defer server.Close()
+
+	err := store.Save(server.URL, &Credentials{Username: "fred", Token: "sometoken"})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	r := &Request{
+		Host:  server.URL,
+		Store: store,
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = r.Get(&User{Username: "fred"})
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		expect(t, err, nil)
+	}
+	expect(t, r.Auth == nil, true)
+}
@@ -0,0 +1,179 @@
+package drudapi
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/drud/drud-go/utils/network"
+)
+
+// MigrationStep records the outcome of one step of a migration
+type MigrationStep struct {
+	Name   string
+	Detail string
+	Err    error
+}
+
+// Migration clones an existing deploy of App into a new deploy using Deploy.MigrateFrom
+type Migration struct {
+	App          *Application
+	Source       string        // name of the deploy being migrated from
+	Target       Deploy        // the new deploy, MigrateFrom is set to Source
+	PollInterval time.Duration // how often to check on the build, defaults to 10s
+	Timeout      time.Duration // how long to wait for the build, defaults to 30m
+	HTTPTimeout  time.Duration // how long to wait for the new deploy to respond, defaults to 60s
+	Secrets      SecretLookup  // reads the new deploy's basic auth when it refers to a secret
+
+	// Backups holds the signed mysql and files backup links of the source deploy
+	Backups []BackUpLink
+	// Build is the build triggered for the new deploy
+	Build *Build
+	// Steps lists each step that has been run
+	Steps []MigrationStep
+	// Report is called after each step, by default steps are logged
+	Report func(step MigrationStep)
+}
+
+// step records and reports a step
+func (m *Migration) step(name string, detail string, err error) error {
+	s := MigrationStep{
+		Name:   name,
+		Detail: detail,
+		Err:    err,
+	}
+	m.Steps = append(m.Steps, s)
+
+	if m.Report != nil {
+		m.Report(s)
+	} else if err != nil {
+		log.Printf("%s: %s", name, err)
+	} else {
+		log.Printf("%s: %s", name, detail)
+	}
+	return err
+}
+
+// Migrate runs the migration m. It creates the target deploy, waits for its build
+// to finish and verifies the new deploy responds.
+func (r *Request) Migrate(m *Migration) error {
+	if m.App == nil {
+		return fmt.Errorf("Migration requires an application")
+	}
+	if m.PollInterval == 0 {
+		m.PollInterval = 10 * time.Second
+	}
+	if m.Timeout == 0 {
+		m.Timeout = 30 * time.Minute
+	}
+	if m.HTTPTimeout == 0 {
+		m.HTTPTimeout = 60 * time.Second
+	}
+
+	if m.App.GetDeploy(m.Source) == nil {
+		return m.step("check deploys", "", fmt.Errorf("No deploy found by name %s", m.Source))
+	}
+	if m.App.GetDeploy(m.Target.Name) != nil {
+		return m.step("check deploys", "", fmt.Errorf("Deploy %s already exists", m.Target.Name))
+	}
+	m.step("check deploys", fmt.Sprintf("migrating %s to %s", m.Source, m.Target.Name), nil)
+
+	// the backups of the source are what the new deploy will be populated with
+	m.Backups = nil
+	for _, kind := range []string{"mysql", "files"} {
+		link := BackUpLink{
+			AppID:    m.App.AppID,
+			DeployID: m.Source,
+			Type:     kind,
+		}
+		err := r.Get(&link)
+		if err != nil {
+			return m.step("fetch "+kind+" backup", "", err)
+		}
+		m.Backups = append(m.Backups, link)
+		m.step("fetch "+kind+" backup", link.URL, nil)
+	}
+
+	m.Target.MigrateFrom = m.Source
+	err := r.AddDeploy(m.App, m.Target)
+	if err != nil {
+		return m.step("create deploy", "", err)
+	}
+	m.step("create deploy", m.Target.Name, nil)
+
+	// the patch response's _updated is when the server created the deploy, so builds
+	// are compared with the server's clock rather than ours
+	m.Build, err = r.waitForBuild(m.App, m.Target.Name, m.App.Updated, m.PollInterval, m.Timeout)
+	if err != nil {
+		return m.step("wait for build", "", err)
+	}
+	if m.Build.State != BuildStateSuccess {
+		return m.step("wait for build", "", fmt.Errorf("Build %s finished with state %s", m.Build.ID, m.Build.State))
+	}
+	m.step("wait for build", fmt.Sprintf("build %s succeeded", m.Build.ID), nil)
+
+	// refresh the app so the new deploy's url is known
+	err = r.Get(m.App)
+	if err != nil {
+		return m.step("verify deploy", "", err)
+	}
+	deploy := m.App.GetDeploy(m.Target.Name)
+	if deploy == nil {
+		return m.step("verify deploy", "", fmt.Errorf("No deploy found by name %s", m.Target.Name))
+	}
+	siteURL := deploy.SiteURL()
+	if siteURL == "" {
+		return m.step("verify deploy", "", fmt.Errorf("Deploy %s has no url", deploy.Name))
+	}
+
+	user, pass, err := deploy.BasicAuthCredentials(m.Secrets)
+	if err != nil {
+		return m.step("verify deploy", "", err)
+	}
+
+	o := network.NewHTTPOptions(siteURL)
+	o.Username = user
+	o.Password = pass
+	o.TickerInterval = 1
+	o.Timeout = m.HTTPTimeout / time.Second
+	err = network.EnsureHTTPStatus(o)
+	if err != nil {
+		return m.step("verify deploy", "", err)
+	}
+	return m.step("verify deploy", siteURL+" responded", nil)
+}
+
+// waitForBuild polls until the newest build of a deploy created no earlier than the eve
+// timestamp since has finished
+func (r *Request) waitForBuild(app *Application, deployName string, since string, interval time.Duration, timeout time.Duration) (*Build, error) {
+	giveUp := time.Now().Add(timeout)
+	for {
+		b, err := r.LatestBuild(app, deployName, "")
+		if err != nil {
+			return nil, err
+		}
+
+		if b != nil && b.Finished() && !createdBefore(b.Created, since) {
+			return b, nil
+		}
+
+		if time.Now().After(giveUp) {
+			return nil, fmt.Errorf("No finished build found for %s after waiting %s", deployName, timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// createdBefore reports whether the eve timestamp created is before since. If either
+// can't be parsed created is treated as not before since.
+func createdBefore(created string, since string) bool {
+	c, err := ParseTime(created)
+	if err != nil {
+		return false
+	}
+	s, err := ParseTime(since)
+	if err != nil {
+		return false
+	}
+	return c.Before(s)
+}
@@ -0,0 +1,117 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMigrate(t *testing.T) {
+	site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, _ := r.BasicAuth()
+		expect(t, user, "drud")
+		expect(t, pass, "secret")
+		fmt.Fprint(w, "hello")
+	}))
+	defer site.Close()
+
+	var patched Application
+	polls := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/gcs/mysql/myapp/production":
+			fmt.Fprint(w, "https://storage/mysql.sql.gz")
+		case r.URL.Path == "/gcs/files/myapp/production":
+			fmt.Fprint(w, "https://storage/files.tar.gz")
+		case r.URL.Path == "/templates":
+			fmt.Fprint(w, `{"_items": [{"name": "wordpress"}, {"name": "drupal"}]}`)
+		case r.URL.Path == "/application/myapp" && r.Method == "PATCH":
+			body, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &patched)
+			fmt.Fprint(w, `{"_etag": "etag2", "_updated": "Mon, 02 Jan 2017 10:00:00 GMT", "_status": "OK"}`)
+		case r.URL.Path == "/application/myapp" && r.Method == "GET":
+			fmt.Fprintf(w, `{"app_id": "myapp", "_id": "app1", "_etag": "etag3", "deploys": [
+				{"name": "production", "protocol": "https", "url": "example.com"},
+				{"name": "clone", "protocol": "http", "url": "%s", "basicauth_user": "drud", "basicauth_pass": "secret"}
+			]}`, strings.TrimPrefix(site.URL, "http://"))
+		case r.URL.Path == "/builds":
+			expect(t, r.URL.Query().Get("where"), `{"application":"app1","deploy_name":"clone"}`)
+			polls++
+			switch polls {
+			case 1:
+				fmt.Fprint(w, `{"_items": []}`)
+			case 2:
+				// left over from an earlier deploy of the same name
+				fmt.Fprint(w, `{"_items": [{"_id": "build0", "state": "failed", "_created": "Sun, 01 Jan 2017 10:00:00 GMT"}]}`)
+			default:
+				// the server's clock, not ours, decides which builds are new
+				fmt.Fprint(w, `{"_items": [{"_id": "build1", "state": "success", "_created": "Mon, 02 Jan 2017 10:00:00 GMT"}]}`)
+			}
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer api.Close()
+
+	r := Request{
+		Host: api.URL,
+		Auth: &Credentials{
+			AdminToken: "dfgdfg",
+		},
+	}
+
+	app := &Application{
+		AppID:   "myapp",
+		ID:      "app1",
+		Etag:    "etag1",
+		Deploys: []Deploy{{Name: "production", Protocol: "https", Url: "example.com"}},
+	}
+
+	var reported []string
+	m := &Migration{
+		App:          app,
+		Source:       "production",
+		Target:       Deploy{Name: "clone", Template: "wordpress"},
+		PollInterval: time.Millisecond,
+		Timeout:      time.Second,
+		HTTPTimeout:  5 * time.Second,
+		Report: func(step MigrationStep) {
+			reported = append(reported, step.Name)
+		},
+	}
+
+	err := r.Migrate(m)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, len(m.Backups), 2)
+	expect(t, m.Backups[0].URL, "https://storage/mysql.sql.gz")
+	expect(t, m.Backups[1].URL, "https://storage/files.tar.gz")
+	expect(t, m.Build.ID, "build1")
+	expect(t, len(patched.Deploys), 2)
+	expect(t, patched.Deploys[1].MigrateFrom, "production")
+	expect(t, strings.Join(reported, ","), "check deploys,fetch mysql backup,fetch files backup,create deploy,wait for build,verify deploy")
+}
+
+func TestMigrateMissingSource(t *testing.T) {
+	r := Request{}
+	m := &Migration{
+		App:    &Application{AppID: "myapp"},
+		Source: "production",
+		Target: Deploy{Name: "clone"},
+		Report: func(step MigrationStep) {},
+	}
+
+	err := r.Migrate(m)
+	refute(t, err, nil)
+	expect(t, len(m.Steps), 1)
+	expect(t, m.Steps[0].Err, err)
+}
@@ -1,5 +1,60 @@
 package drudapi
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PageSize is the max_results requested per page when getting every page of a list
+var PageSize = 50
+
+// eachPage gets every page of the eve list at path, adding query and the paging params
+// to the request's own Query, and passes the body of each page to decode
+func (r *Request) eachPage(path string, query string, decode func(data []byte) error) error {
+	seen := 0
+	for page := 1; ; page++ {
+		params := []string{fmt.Sprintf("page=%d&max_results=%d", page, PageSize)}
+		if query != "" {
+			params = append([]string{query}, params...)
+		}
+		if r.Query != "" {
+			params = append([]string{r.Query}, params...)
+		}
+
+		q := *r
+		q.Query = strings.Join(params, "&")
+
+		raw := &rawResponse{path: path}
+		err := q.Get(raw)
+		if err != nil {
+			return err
+		}
+
+		var list struct {
+			Items []json.RawMessage `json:"_items"`
+			Meta  struct {
+				Total int `json:"total"`
+			} `json:"_meta"`
+		}
+		err = json.Unmarshal(raw.data, &list)
+		if err != nil {
+			return err
+		}
+
+		err = decode(raw.data)
+		if err != nil {
+			return err
+		}
+
+		seen += len(list.Items)
+		if len(list.Items) == 0 || seen >= list.Meta.Total {
+			return nil
+		}
+	}
+}
+
 // Region ...
 type Region struct {
 	Name string `json:"name"`
@@ -18,3 +73,19 @@ func FormatPlural(count int, single string, plural string) string {
 	}
 	return plural
 }
+
+// ParseTime parses the timestamps eve returns in _created and _updated
+func ParseTime(value string) (time.Time, error) {
+	return time.Parse(time.RFC1123, value)
+}
+
+// FormatAge returns a short human readable form of d such as 5m, 3h or 12d
+func FormatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
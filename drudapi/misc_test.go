@@ -0,0 +1,56 @@
+package drudapi
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEachPage(t *testing.T) {
+	defer func(size int) { PageSize = size }(PageSize)
+	PageSize = 2
+
+	pages := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pages++
+		expect(t, r.URL.Path, "/client")
+		expect(t, r.URL.Query().Get("max_results"), "2")
+		expect(t, r.URL.Query().Get("sort"), "name")
+		expect(t, r.URL.Query().Get("embedded"), `{"client":1}`)
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"_items": [{"name": "one"}, {"name": "two"}], "_meta": {"total": 3}}`)
+		case "2":
+			fmt.Fprint(w, `{"_items": [{"name": "three"}], "_meta": {"total": 3}}`)
+		default:
+			t.Errorf("Unexpected page %s", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	// the request's own query, such as embedded fields, is kept on every page
+	r := (&Request{Host: server.URL}).Embed("client")
+
+	var names []string
+	err := r.eachPage("client", "sort=name", func(data []byte) error {
+		list := &ClientList{}
+		err := list.Unmarshal(data)
+		for _, c := range list.Items {
+			names = append(names, c.Name)
+		}
+		return err
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, pages, 2)
+	expect(t, len(names), 3)
+	expect(t, names[2], "three")
+
+	err = r.eachPage("client", "sort=name", func(data []byte) error {
+		return fmt.Errorf("bad page")
+	})
+	refute(t, err, nil)
+}
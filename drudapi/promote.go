@@ -0,0 +1,94 @@
+package drudapi
+
+import "fmt"
+
+// BuildTypePromote is the build type used for builds created by Promote
+const BuildTypePromote = "promote"
+
+// Promotion records a build of one deploy being promoted to another
+type Promotion struct {
+	App    *Application
+	Source string // name of the deploy being promoted from
+	Target string // name of the deploy being promoted to
+	From   *Build // the successful source build whose artifact was promoted
+	Build  *Build // the build created for the target deploy
+}
+
+// Promote builds the target deploy of app from the artifact of the source deploy's latest
+// successful build rather than from the head of the target's branch.
+func (r *Request) Promote(app *Application, source string, target string) (*Promotion, error) {
+	if app.GetDeploy(source) == nil {
+		return nil, fmt.Errorf("No deploy found by name %s", source)
+	}
+	if app.GetDeploy(target) == nil {
+		return nil, fmt.Errorf("No deploy found by name %s", target)
+	}
+
+	from, err := r.LatestBuild(app, source, BuildStateSuccess)
+	if err != nil {
+		return nil, err
+	}
+	if from == nil {
+		return nil, fmt.Errorf("No successful build found for deploy %s", source)
+	}
+	if from.ImageName == "" || from.TagName == "" {
+		return nil, fmt.Errorf("Build %s has no image to promote", from.ID)
+	}
+
+	b := &Build{
+		Type:         BuildTypePromote,
+		Name:         from.Name,
+		CloneURL:     from.CloneURL,
+		Registry:     from.Registry,
+		ImageName:    from.ImageName,
+		TagName:      from.TagName,
+		Branch:       from.Branch,
+		Template:     from.Template,
+		DeployName:   target,
+		Client:       from.Client,
+		Application:  from.Application,
+		PromotedFrom: from.ID,
+	}
+
+	err = r.Post(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Promotion{
+		App:    app,
+		Source: source,
+		Target: target,
+		From:   from,
+		Build:  b,
+	}, nil
+}
+
+// Promotions returns every build of a deploy that was promoted from another deploy,
+// newest first
+func (r *Request) Promotions(app *Application, deployName string) ([]Build, error) {
+	query, err := whereQuery(map[string]interface{}{
+		"application":   app.ID,
+		"deploy_name":   deployName,
+		"promoted_from": map[string]interface{}{"$exists": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var promoted []Build
+	err = r.eachPage(BuildList{}.Path("GET"), query+"&sort=-_created", func(data []byte) error {
+		list := &BuildList{}
+		err := list.Unmarshal(data)
+		for _, b := range list.Items {
+			if b.PromotedFrom != "" {
+				promoted = append(promoted, b)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
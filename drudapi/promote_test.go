@@ -0,0 +1,120 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPromote(t *testing.T) {
+	var posted Build
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/builds" && r.Method == "GET":
+			expect(t, r.URL.Query().Get("where"), `{"application":"app1","deploy_name":"staging","state":"success"}`)
+			expect(t, r.URL.Query().Get("sort"), "-_created")
+			fmt.Fprint(w, `{"_items": [{
+				"_id": "build7",
+				"name": "myapp",
+				"state": "success",
+				"branch": "develop",
+				"template": "wordpress",
+				"image_name": "drud/myapp",
+				"tag_name": "abc123",
+				"deploy_name": "staging"
+			}]}`)
+		case r.URL.Path == "/builds" && r.Method == "POST":
+			body, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &posted)
+			fmt.Fprint(w, `{"_id": "build8", "_etag": "etag1", "_status": "OK"}`)
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+		Auth: &Credentials{
+			AdminToken: "dfgdfg",
+		},
+	}
+
+	app := &Application{
+		AppID: "myapp",
+		ID:    "app1",
+		Deploys: []Deploy{
+			{Name: "staging", Branch: "develop"},
+			{Name: "production", Branch: "master"},
+		},
+	}
+
+	p, err := r.Promote(app, "staging", "production")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, p.From.ID, "build7")
+	expect(t, p.Build.ID, "build8")
+	expect(t, posted.Type, BuildTypePromote)
+	expect(t, posted.DeployName, "production")
+	expect(t, posted.ImageName, "drud/myapp")
+	expect(t, posted.TagName, "abc123")
+	expect(t, posted.Branch, "develop")
+	expect(t, posted.PromotedFrom, "build7")
+
+	_, err = r.Promote(app, "staging", "missing")
+	refute(t, err, nil)
+}
+
+func TestPromoteWithoutSuccessfulBuild(t *testing.T) {
+	server := getTestServer(200, `{"_items": []}`)
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	app := &Application{
+		AppID:   "myapp",
+		Deploys: []Deploy{{Name: "staging"}, {Name: "production"}},
+	}
+
+	_, err := r.Promote(app, "staging", "production")
+	refute(t, err, nil)
+}
+
+func TestPromotionsPages(t *testing.T) {
+	defer func(size int) { PageSize = size }(PageSize)
+	PageSize = 2
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.URL.Path, "/builds")
+		expect(t, r.URL.Query().Get("where"), `{"application":"app1","deploy_name":"production","promoted_from":{"$exists":true}}`)
+		expect(t, r.URL.Query().Get("sort"), "-_created")
+		switch r.URL.Query().Get("page") {
+		case "1":
+			fmt.Fprint(w, `{"_items": [{"_id": "b3", "promoted_from": "b2"}, {"_id": "b2", "promoted_from": ""}], "_meta": {"total": 3}}`)
+		case "2":
+			fmt.Fprint(w, `{"_items": [{"_id": "b1", "promoted_from": "b0"}], "_meta": {"total": 3}}`)
+		default:
+			t.Errorf("Unexpected page %s", r.URL.Query().Get("page"))
+		}
+	}))
+	defer server.Close()
+
+	r := Request{Host: server.URL}
+	promoted, err := r.Promotions(&Application{ID: "app1"}, "production")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, len(promoted), 2)
+	expect(t, promoted[0].ID, "b3")
+	expect(t, promoted[1].ID, "b1")
+}
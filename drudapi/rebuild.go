@@ -0,0 +1,149 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MaxLineage is the most builds BuildLineage will follow back through RetryOf
+var MaxLineage = 100
+
+// buildStatePatch is a patch that only changes the state of a build so the rest of
+// the build is left untouched
+type buildStatePatch struct {
+	ID    string `json:"-"`
+	Etag  string `json:"_etag,omitempty"`
+	State string `json:"state"`
+}
+
+// Path ...
+func (p buildStatePatch) Path(method string) string {
+	return "builds/" + p.ID
+}
+
+// Unmarshal ...
+func (p *buildStatePatch) Unmarshal(data []byte) error {
+	return json.Unmarshal(data, p)
+}
+
+// JSON ...
+func (p buildStatePatch) JSON() []byte {
+	return p.PatchJSON()
+}
+
+// PatchJSON ...
+func (p buildStatePatch) PatchJSON() []byte {
+	jbytes, _ := json.Marshal(map[string]string{"state": p.State})
+	return jbytes
+}
+
+// ETAG ...
+func (p buildStatePatch) ETAG() string {
+	return p.Etag
+}
+
+// CancelBuild asks for a running build to be stopped by setting its state to cancel_requested
+func (r *Request) CancelBuild(b *Build) error {
+	if b.Finished() {
+		return fmt.Errorf("Build %s has already finished with state %s", b.ID, b.State)
+	}
+
+	patch := &buildStatePatch{
+		ID:    b.ID,
+		Etag:  b.Etag,
+		State: BuildStateCancelRequested,
+	}
+	err := r.Patch(patch)
+	if err != nil {
+		return err
+	}
+
+	b.State = BuildStateCancelRequested
+	b.Etag = patch.Etag
+	return nil
+}
+
+// Rebuild creates a new build of the same repo, branch, template and deploy as b
+// linked back to it through RetryOf
+func (r *Request) Rebuild(b *Build) (*Build, error) {
+	if !b.Finished() {
+		return nil, fmt.Errorf("Build %s is still running", b.ID)
+	}
+
+	rebuild := &Build{
+		Type:        b.Type,
+		Name:        b.Name,
+		CloneURL:    b.CloneURL,
+		Registry:    b.Registry,
+		ImageName:   b.ImageName,
+		Branch:      b.Branch,
+		Template:    b.Template,
+		DeployName:  b.DeployName,
+		Client:      b.Client,
+		Application: b.Application,
+		RetryOf:     b.ID,
+	}
+
+	err := r.Post(rebuild)
+	if err != nil {
+		return nil, err
+	}
+	return rebuild, nil
+}
+
+// RetryBuild rebuilds a build that failed or was cancelled
+func (r *Request) RetryBuild(b *Build) (*Build, error) {
+	if b.State != BuildStateFailed && b.State != BuildStateCancelled {
+		return nil, fmt.Errorf("Only failed or cancelled builds can be retried, %s is %s", b.ID, b.State)
+	}
+	return r.Rebuild(b)
+}
+
+// BuildLineage returns the chain of builds that b was retried from, oldest first
+// and ending with b
+func (r *Request) BuildLineage(b *Build) ([]Build, error) {
+	lineage := []Build{*b}
+	seen := map[string]bool{b.ID: true}
+
+	// the caller's query is for b, not the builds it was retried from
+	q := *r
+	q.Query = ""
+
+	current := b
+	for current.RetryOf != "" {
+		if len(lineage) >= MaxLineage || seen[current.RetryOf] {
+			return nil, fmt.Errorf("Lineage of build %s is too long or has a cycle", b.ID)
+		}
+
+		parent := &Build{ID: current.RetryOf}
+		err := q.Get(parent)
+		if err != nil {
+			return nil, err
+		}
+
+		seen[parent.ID] = true
+		lineage = append([]Build{*parent}, lineage...)
+		current = parent
+	}
+	return lineage, nil
+}
+
+// Retries returns the builds created by retrying or rebuilding b, newest first
+func (r *Request) Retries(b *Build) ([]Build, error) {
+	query, err := whereQuery(map[string]interface{}{"retry_of": b.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	var retries []Build
+	err = r.eachPage(BuildList{}.Path("GET"), query+"&sort=-_created", func(data []byte) error {
+		list := &BuildList{}
+		err := list.Unmarshal(data)
+		retries = append(retries, list.Items...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retries, nil
+}
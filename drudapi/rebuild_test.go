@@ -0,0 +1,130 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCancelBuild(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.Method, "PATCH")
+		expect(t, r.URL.Path, "/builds/b1")
+		expect(t, r.Header.Get("If-Match"), "etag1")
+		body, _ := ioutil.ReadAll(r.Body)
+		expect(t, string(body), `{"state":"cancel_requested"}`)
+		fmt.Fprint(w, `{"_id": "b1", "_etag": "etag2", "_status": "OK"}`)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	b := &Build{ID: "b1", Etag: "etag1", State: "building"}
+	err := r.CancelBuild(b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, b.State, BuildStateCancelRequested)
+	expect(t, b.Etag, "etag2")
+
+	err = r.CancelBuild(&Build{ID: "b2", State: BuildStateSuccess})
+	refute(t, err, nil)
+}
+
+func TestRetryBuild(t *testing.T) {
+	var posted Build
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.Method, "POST")
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &posted)
+		fmt.Fprint(w, `{"_id": "b2", "_etag": "etag1", "_status": "OK"}`)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	failed := &Build{
+		ID:         "b1",
+		Name:       "myapp",
+		State:      BuildStateFailed,
+		CloneURL:   "https://github.com/drud/myapp.git",
+		Branch:     "master",
+		Template:   "wordpress",
+		DeployName: "production",
+		Logs:       "boom",
+	}
+
+	retry, err := r.RetryBuild(failed)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, retry.ID, "b2")
+	expect(t, posted.RetryOf, "b1")
+	expect(t, posted.CloneURL, failed.CloneURL)
+	expect(t, posted.Branch, "master")
+	expect(t, posted.Template, "wordpress")
+	expect(t, posted.DeployName, "production")
+	expect(t, posted.State, "")
+	expect(t, posted.Logs, "")
+
+	_, err = r.RetryBuild(&Build{ID: "b3", State: BuildStateSuccess})
+	refute(t, err, nil)
+	_, err = r.Rebuild(&Build{ID: "b4", State: "building"})
+	refute(t, err, nil)
+}
+
+func TestBuildLineage(t *testing.T) {
+	defer func(size int) { PageSize = size }(PageSize)
+	PageSize = 1
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/builds" {
+			expect(t, r.URL.RawQuery, "")
+		}
+		switch r.URL.Path {
+		case "/builds/b2":
+			fmt.Fprint(w, `{"_id": "b2", "retry_of": "b1", "state": "failed"}`)
+		case "/builds/b1":
+			fmt.Fprint(w, `{"_id": "b1", "state": "failed"}`)
+		case "/builds":
+			expect(t, r.URL.Query().Get("where"), `{"retry_of":"b1"}`)
+			expect(t, r.URL.Query().Get("sort"), "-_created")
+			if r.URL.Query().Get("page") == "1" {
+				fmt.Fprint(w, `{"_items": [{"_id": "b4", "retry_of": "b1"}], "_meta": {"total": 2}}`)
+				return
+			}
+			fmt.Fprint(w, `{"_items": [{"_id": "b2", "retry_of": "b1"}], "_meta": {"total": 2}}`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	// the embed is only meant for the build the lineage starts from
+	r := (&Request{Host: server.URL}).Embed("application")
+
+	lineage, err := r.BuildLineage(&Build{ID: "b3", RetryOf: "b2"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(lineage), 3)
+	expect(t, lineage[0].ID, "b1")
+	expect(t, lineage[1].ID, "b2")
+	expect(t, lineage[2].ID, "b3")
+
+	retries, err := r.Retries(&Build{ID: "b1"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(retries), 2)
+	expect(t, retries[0].ID, "b4")
+	expect(t, retries[1].ID, "b2")
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"time"
 )
 
 // Entity interface represents eve entities in some functinos
@@ -35,11 +36,86 @@ type Credentials struct {
 	AdminToken string `json:"admin_token"`
 }
 
+// StatusError is returned when the api responds with a non 2xx status
+type StatusError struct {
+	Status     string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s: %d", e.Status, e.StatusCode)
+}
+
+// IsStatus checks if err is a StatusError with the given status code
+func IsStatus(err error, code int) bool {
+	statusErr, ok := err.(*StatusError)
+	return ok && statusErr.StatusCode == code
+}
+
 // Request type used for building requests
 type Request struct {
-	Host  string // base path of the api  e.g. https://drudapi.genesis.drud.io/v0.1
-	Query string // optional query params e.g. `where={"name":"fred"}``
-	Auth  *Credentials
+	Host    string // base path of the api  e.g. https://drudapi.genesis.drud.io/v0.1
+	Query   string // optional query params e.g. `where={"name":"fred"}``
+	Auth    *Credentials
+	Headers map[string]string // optional extra headers sent with every request
+	Store   *TokenStore       // optional store credentials are read from when Auth is nil
+	Cache   *Cache            // optional cache Get revalidates and falls back to when offline
+	Client  *http.Client      // optional client used to send requests, e.g. to time them
+}
+
+// httpClient returns the Client or a default client if it has not been set
+func (r *Request) httpClient() *http.Client {
+	if r.Client != nil {
+		return r.Client
+	}
+	return &http.Client{}
+}
+
+// NewRequest returns a request for host which loads its credentials from the
+// default token store
+func NewRequest(host string) (*Request, error) {
+	store, err := DefaultTokenStore()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Request{
+		Host:  host,
+		Store: store,
+	}, nil
+}
+
+// credentials returns Auth, or the credentials stored for Host when Auth is nil.
+// The request is not modified so it can be shared between goroutines.
+func (r *Request) credentials() (*Credentials, error) {
+	if r.Auth != nil || r.Store == nil {
+		return r.Auth, nil
+	}
+	return r.Store.Load(r.Host)
+}
+
+// authorize sets the auth and extra headers on req
+func (r *Request) authorize(req *http.Request) error {
+	creds, err := r.credentials()
+	if err != nil {
+		return err
+	}
+
+	if creds != nil {
+		// check for admin token, then auth token, then user Credentials
+		if creds.AdminToken != "" {
+			req.Header.Set("Authorization", "token "+creds.AdminToken)
+		} else if creds.Token != "" {
+			req.Header.Set("Authorization", "Bearer "+creds.Token)
+		} else {
+			req.SetBasicAuth(creds.Username, creds.Password)
+		}
+	}
+
+	for header, value := range r.Headers {
+		req.Header.Set(header, value)
+	}
+	return nil
 }
 
 // Get ...
@@ -58,20 +134,36 @@ func (r *Request) Get(entity EntityGetter) error {
 
 	req.Header.Set("Content-Type", "application/json")
 
-	if r.Auth != nil {
-		// check for admin token, then auth token, then user Credentials
-		if r.Auth.AdminToken != "" {
-			req.Header.Set("Authorization", "token "+r.Auth.AdminToken)
-		} else if r.Auth.Token != "" {
-			req.Header.Set("Authorization", "Bearer "+r.Auth.Token)
-		} else {
-			req.SetBasicAuth(r.Auth.Username, r.Auth.Password)
+	err = r.authorize(req)
+	if err != nil {
+		return err
+	}
+
+	var cached *CacheEntry
+	var identity string
+	if r.Cache != nil {
+		var creds *Credentials
+		creds, err = r.credentials()
+		if err != nil {
+			return err
+		}
+		identity, err = r.Cache.Identity(creds)
+		if err != nil {
+			return err
+		}
+		cached = r.Cache.Load(u.String(), identity)
+		if cached != nil && cached.Etag != "" {
+			req.Header.Set("If-None-Match", cached.Etag)
 		}
 	}
 
-	client := &http.Client{}
+	client := r.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
+		if cached != nil {
+			log.Printf("Warning: %s is unreachable, using %s cached %s ago: %s", r.Host, entity.Path("GET"), FormatAge(time.Since(cached.Fetched)), err)
+			return entity.Unmarshal(cached.Body)
+		}
 		return err
 	}
 
@@ -81,10 +173,16 @@ func (r *Request) Get(entity EntityGetter) error {
 		return err
 	}
 
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		cached.Fetched = time.Now()
+		r.Cache.Save(cached)
+		return entity.Unmarshal(cached.Body)
+	}
+
 	// Handle different status codes
 	if resp.StatusCode-200 > 100 {
-		log.Println(string(body))
-		return fmt.Errorf("%s: %d", resp.Status, resp.StatusCode)
+		log.Println(redact(body))
+		return &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
 	}
 
 	err = entity.Unmarshal(body)
@@ -92,6 +190,28 @@ func (r *Request) Get(entity EntityGetter) error {
 		return err
 	}
 
+	if r.Cache != nil {
+		if data, stripped := cacheable(entity.Path("GET"), body); data != nil {
+			etag := responseEtag(resp.Header.Get("ETag"), body)
+			if stripped {
+				// without its secrets the entry is only good as an offline fallback,
+				// never as the answer to a 304
+				etag = ""
+			}
+			err = r.Cache.Save(&CacheEntry{
+				URL:      u.String(),
+				Identity: identity,
+				Path:     entity.Path("GET"),
+				Etag:     etag,
+				Body:     data,
+				Fetched:  time.Now(),
+			})
+			if err != nil {
+				log.Printf("Could not cache %s: %s", entity.Path("GET"), err)
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -100,6 +220,20 @@ func (r *Request) Post(entity Entity) error {
 	var req *http.Request
 	var err error
 
+	err = validate(entity, "POST")
+	if err != nil {
+		return err
+	}
+
+	// templates are only checked for new deploys so retiring one never blocks a post
+	// of something else
+	if deploys := newDeploys(entity); deploys != nil {
+		err = r.CheckTemplates(deploys...)
+		if err != nil {
+			return err
+		}
+	}
+
 	u, err := url.Parse(r.Host)
 	u.Path = path.Join(u.Path, entity.Path("POST"))
 
@@ -110,18 +244,12 @@ func (r *Request) Post(entity Entity) error {
 
 	req.Header.Set("Content-Type", "application/json")
 
-	if r.Auth != nil {
-		// check for admin token, then auth token, then user Credentials
-		if r.Auth.AdminToken != "" {
-			req.Header.Set("Authorization", "token "+r.Auth.AdminToken)
-		} else if r.Auth.Token != "" {
-			req.Header.Set("Authorization", "Bearer "+r.Auth.Token)
-		} else {
-			req.SetBasicAuth(r.Auth.Username, r.Auth.Password)
-		}
+	err = r.authorize(req)
+	if err != nil {
+		return err
 	}
 
-	client := &http.Client{}
+	client := r.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -136,10 +264,12 @@ func (r *Request) Post(entity Entity) error {
 	// Handle different status codes
 	if resp.StatusCode-200 > 100 {
 		log.Println(u.String())
-		log.Println(string(body))
-		return fmt.Errorf("%s: %d", resp.Status, resp.StatusCode)
+		log.Println(redact(body))
+		return &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
 	}
 
+	r.invalidate(entity.Path("POST"))
+
 	err = entity.Unmarshal(body)
 	if err != nil {
 		return err
@@ -153,6 +283,11 @@ func (r *Request) Patch(entity Entity) error {
 	var req *http.Request
 	var err error
 
+	err = validate(entity, "PATCH")
+	if err != nil {
+		return err
+	}
+
 	u, err := url.Parse(r.Host)
 	u.Path = path.Join(u.Path, entity.Path("PATCH"))
 
@@ -164,18 +299,12 @@ func (r *Request) Patch(entity Entity) error {
 	req.Header.Set("If-Match", entity.ETAG())
 	req.Header.Set("Content-Type", "application/json")
 
-	if r.Auth != nil {
-		// check for admin token, then auth token, then user Credentials
-		if r.Auth.AdminToken != "" {
-			req.Header.Set("Authorization", "token "+r.Auth.AdminToken)
-		} else if r.Auth.Token != "" {
-			req.Header.Set("Authorization", "Bearer "+r.Auth.Token)
-		} else {
-			req.SetBasicAuth(r.Auth.Username, r.Auth.Password)
-		}
+	err = r.authorize(req)
+	if err != nil {
+		return err
 	}
 
-	client := &http.Client{}
+	client := r.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -189,10 +318,12 @@ func (r *Request) Patch(entity Entity) error {
 
 	// Handle different status codes
 	if resp.StatusCode-200 > 100 {
-		log.Println(string(body))
-		return fmt.Errorf("%s: %d", resp.Status, resp.StatusCode)
+		log.Println(redact(body))
+		return &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
 	}
 
+	r.invalidate(entity.Path("PATCH"))
+
 	err = entity.Unmarshal(body)
 	if err != nil {
 		return err
@@ -217,18 +348,12 @@ func (r *Request) Delete(entity Entity) error {
 	req.Header.Set("If-Match", entity.ETAG())
 	req.Header.Set("Content-Type", "application/json")
 
-	if r.Auth != nil {
-		// check for admin token, then auth token, then user Credentials
-		if r.Auth.AdminToken != "" {
-			req.Header.Set("Authorization", "token "+r.Auth.AdminToken)
-		} else if r.Auth.Token != "" {
-			req.Header.Set("Authorization", "Bearer "+r.Auth.Token)
-		} else {
-			req.SetBasicAuth(r.Auth.Username, r.Auth.Password)
-		}
+	err = r.authorize(req)
+	if err != nil {
+		return err
 	}
 
-	client := &http.Client{}
+	client := r.httpClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
@@ -236,8 +361,9 @@ func (r *Request) Delete(entity Entity) error {
 
 	// Handle different status codes
 	if resp.StatusCode-200 > 100 {
-		return fmt.Errorf("%s: %d", resp.Status, resp.StatusCode)
+		return &StatusError{Status: resp.Status, StatusCode: resp.StatusCode}
 	}
 
+	r.invalidate(entity.Path("DELETE"))
 	return nil
 }
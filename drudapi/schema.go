@@ -0,0 +1,305 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// SchemaVersion is the JSON Schema draft generated schemas declare
+const SchemaVersion = "http://json-schema.org/draft-04/schema#"
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// Schema generates a JSON Schema for the entity v from its json tags. Fields without
+// omitempty are required, fields the struct does not declare are allowed so CheckContract
+// can report them as Unknown, and nested entities that eve can return as a bare _id
+// accept either the _id or the embedded object.
+func Schema(v interface{}) map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(v), map[reflect.Type]bool{})
+	// only nested entities can be a bare _id
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		schema = oneOf[1].(map[string]interface{})
+	}
+	schema["$schema"] = SchemaVersion
+	return schema
+}
+
+// EntitySchemas returns the schemas of the drudapi entities by name
+func EntitySchemas() map[string]map[string]interface{} {
+	return map[string]map[string]interface{}{
+		"application": Schema(Application{}),
+		"build":       Schema(Build{}),
+		"client":      Schema(Client{}),
+		"container":   Schema(Container{}),
+		"deploy":      Schema(Deploy{}),
+		"user":        Schema(User{}),
+	}
+}
+
+// SchemaJSON returns the indented schema of the entity v
+func SchemaJSON(v interface{}) ([]byte, error) {
+	return json.MarshalIndent(Schema(v), "", "  ")
+}
+
+// jsonField returns the json name of a struct field and whether it is omitted when empty
+func jsonField(f reflect.StructField) (string, bool, bool) {
+	if f.PkgPath != "" {
+		return "", false, false
+	}
+
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if name == "" {
+		name = f.Name
+	}
+	omitempty := false
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, true
+}
+
+// typeSchema returns the schema of t, seen guards against recursive types
+func typeSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == rawMessageType {
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return map[string]interface{}{"type": "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := make(map[string]interface{})
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			name, omitempty, ok := jsonField(t.Field(i))
+			if !ok {
+				continue
+			}
+			properties[name] = typeSchema(t.Field(i).Type, seen)
+			if !omitempty {
+				required = append(required, name)
+			}
+		}
+
+		// undeclared fields are allowed here and reported as Unknown by CheckContract
+		schema := map[string]interface{}{
+			"type":       "object",
+			"title":      t.Name(),
+			"properties": properties,
+		}
+		if len(required) > 0 {
+			sort.Strings(required)
+			schema["required"] = required
+		}
+
+		if _, ok := reflect.PtrTo(t).MethodByName("IsReference"); ok {
+			return map[string]interface{}{
+				"title": t.Name(),
+				"oneOf": []interface{}{map[string]interface{}{"type": "string"}, schema},
+			}
+		}
+		return schema
+	}
+	return map[string]interface{}{}
+}
+
+// ContractReport lists the differences between an api response and an entity's schema.
+// Fields are named by their path with list indexes removed e.g. deploys[].name.
+type ContractReport struct {
+	Errors  []string // schema violations such as wrong types or missing required fields
+	Unknown []string // fields in the response the entity does not declare
+	Missing []string // fields the entity declares that never appeared in the response
+	Empty   bool     // the response was a list with no items so nothing was checked
+
+	declared map[string]bool
+	present  map[string]bool
+}
+
+// OK reports whether the response had something to check and matched the schema
+// with no unknown fields
+func (c *ContractReport) OK() bool {
+	return !c.Empty && len(c.Errors) == 0 && len(c.Unknown) == 0
+}
+
+// String summarises the report
+func (c *ContractReport) String() string {
+	var lines []string
+	if c.Empty {
+		lines = append(lines, "error: list response has no items to check")
+	}
+	for _, e := range c.Errors {
+		lines = append(lines, "error: "+e)
+	}
+	for _, f := range c.Unknown {
+		lines = append(lines, "unknown field: "+f)
+	}
+	for _, f := range c.Missing {
+		lines = append(lines, "missing field: "+f)
+	}
+	if len(lines) == 0 {
+		return "response matches schema"
+	}
+	return strings.Join(lines, "\n")
+}
+
+// CheckContract validates an api response against a schema from Schema. Eve list
+// responses are checked item by item and a field is only missing if no item has it.
+func CheckContract(schema map[string]interface{}, data []byte) (*ContractReport, error) {
+	var doc interface{}
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("Response is not json: %s", err)
+	}
+
+	docs := []interface{}{doc}
+	prefix := ""
+	if obj, ok := doc.(map[string]interface{}); ok {
+		if items, ok := obj["_items"].([]interface{}); ok {
+			docs = items
+			prefix = "_items"
+		}
+	}
+
+	report := &ContractReport{
+		Empty:    len(docs) == 0,
+		declared: make(map[string]bool),
+		present:  make(map[string]bool),
+	}
+	unknown := make(map[string]bool)
+	for i, d := range docs {
+		result, err := gojsonschema.Validate(gojsonschema.NewGoLoader(schema), gojsonschema.NewGoLoader(d))
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range result.Errors() {
+			field := e.Field()
+			if prefix != "" {
+				field = fmt.Sprintf("%s[%d].%s", prefix, i, field)
+			}
+			report.Errors = append(report.Errors, field+": "+e.Description())
+		}
+
+		report.compare(schema, d, "", unknown)
+	}
+
+	for f := range unknown {
+		report.Unknown = append(report.Unknown, f)
+	}
+	for f := range report.declared {
+		if !report.present[f] {
+			report.Missing = append(report.Missing, f)
+		}
+	}
+	sort.Strings(report.Unknown)
+	sort.Strings(report.Missing)
+	return report, nil
+}
+
+// compare records the fields of doc the schema declares, the ones doc has and the ones
+// the schema does not declare
+func (c *ContractReport) compare(schema map[string]interface{}, doc interface{}, path string, unknown map[string]bool) {
+	join := func(name string) string {
+		if path == "" {
+			return name
+		}
+		return path + "." + name
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if _, ref := doc.(string); ref {
+			return
+		}
+		schema = oneOf[1].(map[string]interface{})
+	}
+
+	switch value := doc.(type) {
+	case map[string]interface{}:
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		for name := range properties {
+			c.declared[join(name)] = true
+		}
+		for name, v := range value {
+			property, ok := properties[name].(map[string]interface{})
+			if !ok {
+				if !strings.HasPrefix(name, "_") {
+					unknown[join(name)] = true
+				}
+				continue
+			}
+			c.present[join(name)] = true
+			c.compare(property, v, join(name), unknown)
+		}
+	case []interface{}:
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		for _, v := range value {
+			c.compare(items, v, path+"[]", unknown)
+		}
+	}
+}
+
+// rawResponse keeps the body of a GET so it can be checked against a schema
+type rawResponse struct {
+	path string
+	data []byte
+}
+
+// Path ...
+func (r rawResponse) Path(method string) string {
+	return r.path
+}
+
+// Unmarshal ...
+func (r *rawResponse) Unmarshal(data []byte) error {
+	r.data = data
+	return nil
+}
+
+// CheckLiveContract gets path from the api and checks the response against the schema
+// of the entity v e.g. r.CheckLiveContract("application/myapp", Application{})
+func (r *Request) CheckLiveContract(path string, v interface{}) (*ContractReport, error) {
+	resp := &rawResponse{path: path}
+	err := r.Get(resp)
+	if err != nil {
+		return nil, err
+	}
+	return CheckContract(Schema(v), resp.data)
+}
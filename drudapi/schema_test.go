@@ -0,0 +1,108 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	schema := Schema(Application{})
+	expect(t, schema["$schema"], SchemaVersion)
+	expect(t, schema["additionalProperties"], nil)
+
+	properties := schema["properties"].(map[string]interface{})
+	expect(t, properties["app_id"].(map[string]interface{})["type"], "string")
+	expect(t, properties["github_hook_id"].(map[string]interface{})["type"], "integer")
+	expect(t, properties["Extra"], nil)
+	expect(t, properties["relation"], nil)
+
+	// the client can be returned as a bare _id or embedded
+	client := properties["client"].(map[string]interface{})
+	expect(t, len(client["oneOf"].([]interface{})), 2)
+
+	deploys := properties["deploys"].(map[string]interface{})
+	expect(t, deploys["type"], "array")
+	deploy := deploys["items"].(map[string]interface{})
+	expect(t, deploy["title"], "Deploy")
+
+	for name, s := range EntitySchemas() {
+		_, err := json.Marshal(s)
+		if err != nil {
+			log.Fatalf("%s: %s", name, err)
+		}
+	}
+}
+
+func TestCheckContract(t *testing.T) {
+	recorded := []byte(`{
+		"_id": "58f8d5a3",
+		"_etag": "etag1",
+		"_links": {"self": {"href": "application/myapp"}},
+		"app_id": "myapp",
+		"name": "My App",
+		"github_hook_id": "1234",
+		"client": "58f8d5a0",
+		"deploys": [
+			{"name": "production", "branch": "master", "php_version": "7.1"},
+			{"name": "staging", "branch": "develop"}
+		]
+	}`)
+
+	report, err := CheckContract(Schema(Application{}), recorded)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, report.OK(), false)
+	expect(t, len(report.Unknown), 1)
+	expect(t, report.Unknown[0], "deploys[].php_version")
+	// unknown fields are only reported as unknown, not as schema errors too
+	expect(t, len(report.Errors), 1)
+	expect(t, strings.Contains(report.Errors[0], "github_hook_id"), true)
+
+	missing := strings.Join(report.Missing, " ")
+	expect(t, strings.Contains(missing, "slack_channel"), true)
+	expect(t, strings.Contains(missing, "deploys[].template"), true)
+	expect(t, strings.Contains(missing, "deploys[].branch"), false)
+	expect(t, strings.Contains(missing, "client.name"), false)
+}
+
+func TestCheckContractEmptyList(t *testing.T) {
+	report, err := CheckContract(Schema(Application{}), []byte(`{"_items": [], "_meta": {"total": 0}}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, report.Empty, true)
+	expect(t, report.OK(), false)
+	expect(t, strings.Contains(report.String(), "no items"), true)
+
+	report, err = CheckContract(Schema(Application{}), []byte(`{"_items": [{"app_id": "myapp"}], "_meta": {"total": 1}}`))
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, report.Empty, false)
+}
+
+func TestCheckLiveContract(t *testing.T) {
+	server := getTestServer(200, `{"_items": [
+		{"_id": "1", "_etag": "a", "_created": "Mon, 02 Jan 2017 10:00:00 GMT", "_updated": "Mon, 02 Jan 2017 10:00:00 GMT", "name": "drud", "email": "ops@drud.com"},
+		{"_id": "2", "_etag": "b", "_created": "Mon, 02 Jan 2017 10:00:00 GMT", "_updated": "Mon, 02 Jan 2017 10:00:00 GMT", "name": "acme", "phone": "555-0100", "repo_org": "acme"}
+	], "_meta": {"total": 2}}`)
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	report, err := r.CheckLiveContract("client", Client{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, report.OK(), true)
+	// every field appeared in at least one item
+	expect(t, len(report.Missing), 0)
+	expect(t, report.String(), "response matches schema")
+}
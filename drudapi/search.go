@@ -0,0 +1,401 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/gosuri/uitable"
+)
+
+// Scores given to a search term by how it matches a field value, before the field's weight
+const (
+	matchExact     = 100
+	matchPrefix    = 75
+	matchSubstring = 50
+	matchTypo      = 35
+	matchFuzzy     = 20
+)
+
+// searchWeights scales the score of a match by the field it was found in
+var searchWeights = map[string]float64{
+	"app_id":   1,
+	"name":     1,
+	"client":   0.9,
+	"deploy":   0.8,
+	"hostname": 0.8,
+	"repo":     0.6,
+}
+
+// SearchDoc is the searchable summary of an application
+type SearchDoc struct {
+	ID        string   `json:"_id"`
+	AppID     string   `json:"app_id"`
+	Name      string   `json:"name"`
+	Client    string   `json:"client"`
+	Deploys   []string `json:"deploys"`
+	Hostnames []string `json:"hostnames"`
+	Repos     []string `json:"repos"`
+	Updated   string   `json:"_updated"`
+}
+
+// fields returns each searchable value of the doc keyed by field
+func (d SearchDoc) fields() map[string][]string {
+	return map[string][]string{
+		"app_id":   {d.AppID},
+		"name":     {d.Name},
+		"client":   {d.Client},
+		"deploy":   d.Deploys,
+		"hostname": d.Hostnames,
+		"repo":     d.Repos,
+	}
+}
+
+// newSearchDoc summarises app, whose client is called client, for the search index
+func newSearchDoc(app Application, client string) SearchDoc {
+	doc := SearchDoc{
+		ID:      app.ID,
+		AppID:   app.AppID,
+		Name:    app.Name,
+		Client:  client,
+		Updated: app.Updated,
+	}
+
+	for _, d := range app.Deploys {
+		doc.Deploys = append(doc.Deploys, d.Name)
+		for _, host := range []string{d.Hostname, d.Url} {
+			if host != "" {
+				doc.Hostnames = append(doc.Hostnames, host)
+			}
+		}
+		for _, h := range d.Hostnames {
+			doc.Hostnames = append(doc.Hostnames, h.Name)
+		}
+	}
+
+	if app.Repo != "" {
+		doc.Repos = append(doc.Repos, app.Repo)
+		if app.RepoOrg != "" {
+			doc.Repos = append(doc.Repos, app.RepoOrg+"/"+app.Repo)
+		}
+	}
+	if app.RepoDetails != nil && app.RepoDetails.CloneURL != "" {
+		doc.Repos = append(doc.Repos, app.RepoDetails.CloneURL)
+	}
+	return doc
+}
+
+// SearchIndex is a local index of applications that is refreshed incrementally from the
+// api using _updated
+type SearchIndex struct {
+	Path    string               `json:"-"`
+	Updated string               `json:"updated"` // latest _updated of any indexed application
+	Docs    map[string]SearchDoc `json:"docs"`    // keyed by _id
+	lock    sync.RWMutex
+}
+
+// DefaultSearchIndexPath is drud/search.json in the user's cache dir
+func DefaultSearchIndexPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drud", "search.json"), nil
+}
+
+// LoadSearchIndex reads the index saved at path, returning an empty index if there is none
+func LoadSearchIndex(path string) (*SearchIndex, error) {
+	idx := &SearchIndex{
+		Path: path,
+		Docs: make(map[string]SearchDoc),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, idx)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read search index %s: %s", path, err)
+	}
+	if idx.Docs == nil {
+		idx.Docs = make(map[string]SearchDoc)
+	}
+	return idx, nil
+}
+
+// Save writes the index to its Path
+func (idx *SearchIndex) Save() error {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	err := os.MkdirAll(filepath.Dir(idx.Path), 0700)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(idx.Path, data, 0600)
+}
+
+// Add indexes app, replacing any earlier version of it. The client's name is only
+// indexed if it is embedded in app.
+func (idx *SearchIndex) Add(app Application) {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.Docs == nil {
+		idx.Docs = make(map[string]SearchDoc)
+	}
+	idx.Docs[searchKey(app)] = newSearchDoc(app, app.Client.Name)
+	idx.Updated = laterUpdated(idx.Updated, app.Updated)
+}
+
+// searchKey returns the key app is indexed under
+func searchKey(app Application) string {
+	if app.ID == "" {
+		return app.AppID
+	}
+	return app.ID
+}
+
+// laterUpdated returns whichever of the _updated times latest and updated is later
+func laterUpdated(latest string, updated string) string {
+	if updated == "" {
+		return latest
+	}
+	u, err := ParseTime(updated)
+	if err != nil {
+		return latest
+	}
+	l, err := ParseTime(latest)
+	if err != nil || u.After(l) {
+		return updated
+	}
+	return latest
+}
+
+// RefreshSearchIndex adds the applications updated since the index was last refreshed,
+// or every application if it never has been. Deleted applications are only dropped by a
+// full refresh, which can be forced by clearing Updated. The index is left as it was if
+// any page can't be fetched, so the next refresh starts from the same point.
+func (r *Request) RefreshSearchIndex(idx *SearchIndex) (int, error) {
+	idx.lock.RLock()
+	since := idx.Updated
+	idx.lock.RUnlock()
+
+	var query string
+	if since != "" {
+		// $gte so applications updated in the same second as the last refresh are not missed
+		var err error
+		query, err = whereQuery(map[string]interface{}{
+			"_updated": map[string]interface{}{"$gte": since},
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	clients, err := r.AllClients()
+	if err != nil {
+		return 0, err
+	}
+	names := ClientNamesByID(clients)
+
+	docs := make(map[string]SearchDoc)
+	updated := since
+	err = r.eachPage(ApplicationList{}.Path("GET"), query, func(data []byte) error {
+		list := &ApplicationList{}
+		err := list.Unmarshal(data)
+		if err != nil {
+			return err
+		}
+
+		for _, app := range list.Items {
+			docs[searchKey(app)] = newSearchDoc(app, app.ClientName(names))
+			updated = laterUpdated(updated, app.Updated)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if since == "" || idx.Docs == nil {
+		idx.Docs = docs
+	} else {
+		for key, doc := range docs {
+			idx.Docs[key] = doc
+		}
+	}
+	idx.Updated = updated
+	return len(docs), nil
+}
+
+// SearchResult is an application matching a search along with how well it matched
+type SearchResult struct {
+	Doc     SearchDoc
+	Score   int
+	Matches []string // field:value of the best match for each term
+}
+
+// Search returns the indexed applications matching every term of query, best match
+// first. Terms match field values exactly, by prefix, as a substring, with a single typo
+// or as an in order subsequence of letters.
+func (idx *SearchIndex) Search(query string) []SearchResult {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	var results []SearchResult
+	for _, doc := range idx.Docs {
+		result := SearchResult{Doc: doc}
+		fields := doc.fields()
+
+		for _, term := range terms {
+			best, match := 0, ""
+			for field, values := range fields {
+				for _, value := range values {
+					score := int(float64(matchValue(term, strings.ToLower(value))) * searchWeights[field])
+					if score > best || (score == best && score > 0 && field+":"+value < match) {
+						best, match = score, field+":"+value
+					}
+				}
+			}
+			if best == 0 {
+				result.Score = 0
+				break
+			}
+			result.Score += best
+			result.Matches = append(result.Matches, match)
+		}
+
+		if result.Score > 0 {
+			results = append(results, result)
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Doc.AppID < results[j].Doc.AppID
+	})
+	return results
+}
+
+// matchValue scores how well term matches value or any of the words in it
+func matchValue(term string, value string) int {
+	if value == "" {
+		return 0
+	}
+
+	best := matchTerm(term, value)
+	words := strings.FieldsFunc(value, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+	for _, word := range words {
+		if score := matchTerm(term, word); score > best {
+			best = score
+		}
+	}
+	return best
+}
+
+// matchTerm scores how well term matches a single value
+func matchTerm(term string, value string) int {
+	switch {
+	case term == value:
+		return matchExact
+	case strings.HasPrefix(value, term):
+		return matchPrefix
+	case strings.Contains(value, term):
+		return matchSubstring
+	case len(term) >= 4 && editDistance(term, value) == 1:
+		return matchTypo
+	case len(term) >= 3 && isSubsequence(term, value):
+		return matchFuzzy
+	}
+	return 0
+}
+
+// isSubsequence reports whether the letters of term appear in value in order
+func isSubsequence(term string, value string) bool {
+	t := []rune(term)
+	i := 0
+	for _, c := range value {
+		if i < len(t) && c == t[i] {
+			i++
+		}
+	}
+	return i == len(t)
+}
+
+// editDistance is the levenshtein distance between a and b
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j] + 1
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+			if prev[j-1]+cost < cur[j] {
+				cur[j] = prev[j-1] + cost
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// DescribeSearch pretty prints search results
+func DescribeSearch(results []SearchResult) {
+	fmt.Printf("%v %v found.\n\n", len(results), FormatPlural(len(results), "application", "applications"))
+
+	table := uitable.New()
+	table.MaxColWidth = 50
+	table.AddRow("APP", "NAME", "CLIENT", "DEPLOYS", "SCORE", "MATCHED")
+	for _, result := range results {
+		table.AddRow(
+			result.Doc.AppID,
+			result.Doc.Name,
+			result.Doc.Client,
+			strings.Join(result.Doc.Deploys, ", "),
+			result.Score,
+			strings.Join(result.Matches, ", "),
+		)
+	}
+	fmt.Println(table)
+}
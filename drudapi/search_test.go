@@ -0,0 +1,165 @@
+package drudapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	idx := &SearchIndex{}
+	idx.Add(Application{
+		ID:      "1",
+		AppID:   "acmesite",
+		Name:    "Acme Marketing Site",
+		Client:  Client{Name: "acme"},
+		Repo:    "acme-www",
+		RepoOrg: "acmecorp",
+		Deploys: []Deploy{
+			{Name: "production", Url: "acmesite-production.drud.io", Hostnames: []CustomHostname{{Name: "www.acme.com"}}},
+			{Name: "staging", Url: "acmesite-staging.drud.io"},
+		},
+	})
+	idx.Add(Application{
+		ID:      "2",
+		AppID:   "acmeshop",
+		Name:    "Acme Shop",
+		Client:  Client{Name: "acme"},
+		Deploys: []Deploy{{Name: "production"}},
+	})
+	idx.Add(Application{
+		ID:      "3",
+		AppID:   "blog",
+		Name:    "Company Blog",
+		Client:  Client{Name: "globex"},
+		Deploys: []Deploy{{Name: "staging"}},
+	})
+
+	results := idx.Search("acme staging")
+	expect(t, len(results), 1)
+	expect(t, results[0].Doc.AppID, "acmesite")
+	expect(t, results[0].Matches[1], "deploy:staging")
+
+	// exact and prefix matches rank above substring matches
+	results = idx.Search("acme")
+	expect(t, len(results), 2)
+	expect(t, results[0].Doc.AppID, "acmeshop")
+	expect(t, results[1].Doc.AppID, "acmesite")
+
+	expect(t, idx.Search("globx")[0].Doc.AppID, "blog")
+	expect(t, idx.Search("www.acme.com")[0].Doc.AppID, "acmesite")
+	expect(t, idx.Search("acmecorp/acme-www")[0].Doc.AppID, "acmesite")
+	expect(t, idx.Search("prd")[0].Doc.Deploys[0], "production")
+	expect(t, len(idx.Search("nothing")), 0)
+	expect(t, len(idx.Search("")), 0)
+}
+
+func TestRefreshSearchIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drudapi-search")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/client" {
+			fmt.Fprint(w, `{"_items": [{"_id": "c1", "name": "Initech"}], "_meta": {"total": 1}}`)
+			return
+		}
+		queries = append(queries, r.URL.Query().Get("where"))
+		if r.URL.Query().Get("where") == "" {
+			fmt.Fprint(w, `{"_items": [
+				{"_id": "1", "app_id": "one", "name": "First", "client": "c1", "_updated": "Mon, 02 Jan 2017 10:00:00 GMT"},
+				{"_id": "2", "app_id": "two", "name": "Second", "_updated": "Tue, 03 Jan 2017 10:00:00 GMT"}
+			], "_meta": {"total": 2}}`)
+			return
+		}
+		fmt.Fprint(w, `{"_items": [
+			{"_id": "1", "app_id": "one", "name": "Renamed", "_updated": "Wed, 04 Jan 2017 10:00:00 GMT"}
+		], "_meta": {"total": 1}}`)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	idx, err := LoadSearchIndex(filepath.Join(dir, "search.json"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	count, err := r.RefreshSearchIndex(idx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, count, 2)
+	expect(t, idx.Updated, "Tue, 03 Jan 2017 10:00:00 GMT")
+
+	err = idx.Save()
+	if err != nil {
+		log.Fatal(err)
+	}
+	idx, err = LoadSearchIndex(idx.Path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(idx.Docs), 2)
+	expect(t, idx.Docs["1"].Client, "Initech")
+
+	count, err = r.RefreshSearchIndex(idx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, count, 1)
+	expect(t, queries[1], `{"_updated":{"$gte":"Tue, 03 Jan 2017 10:00:00 GMT"}}`)
+	expect(t, idx.Updated, "Wed, 04 Jan 2017 10:00:00 GMT")
+	expect(t, len(idx.Docs), 2)
+	expect(t, idx.Search("renamed")[0].Doc.AppID, "one")
+	expect(t, len(idx.Search("first")), 0)
+}
+
+func TestRefreshSearchIndexFailure(t *testing.T) {
+	defer func(size int) { PageSize = size }(PageSize)
+	PageSize = 1
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/client":
+			fmt.Fprint(w, `{"_items": [], "_meta": {"total": 0}}`)
+		case r.URL.Query().Get("page") == "1":
+			fmt.Fprint(w, `{"_items": [
+				{"_id": "2", "app_id": "two", "name": "Second", "_updated": "Wed, 04 Jan 2017 10:00:00 GMT"}
+			], "_meta": {"total": 2}}`)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	idx := &SearchIndex{}
+	idx.Add(Application{ID: "1", AppID: "one", Name: "First", Updated: "Mon, 02 Jan 2017 10:00:00 GMT"})
+
+	// a failure on a later page leaves the index as it was so the next refresh
+	// fetches the same applications again
+	_, err := r.RefreshSearchIndex(idx)
+	refute(t, err, nil)
+	expect(t, idx.Updated, "Mon, 02 Jan 2017 10:00:00 GMT")
+	expect(t, len(idx.Docs), 1)
+	expect(t, idx.Docs["1"].Name, "First")
+
+	idx.Updated = ""
+	_, err = r.RefreshSearchIndex(idx)
+	refute(t, err, nil)
+	expect(t, len(idx.Docs), 1)
+}
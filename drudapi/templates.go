@@ -0,0 +1,267 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// Template is a site template deploys and builds refer to by name
+type Template struct {
+	Created     string `json:"_created,omitempty"`
+	Etag        string `json:"_etag,omitempty"`
+	ID          string `json:"_id,omitempty"`
+	Updated     string `json:"_updated,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	// Extra holds fields returned by the api that Template does not know about
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the Template and keeps any fields it does not know about in Extra
+func (t *Template) UnmarshalJSON(data []byte) error {
+	type plain Template
+	return unmarshalWithExtra(data, (*plain)(t), &t.Extra)
+}
+
+// MarshalJSON encodes the Template along with the fields kept in Extra
+func (t Template) MarshalJSON() ([]byte, error) {
+	type plain Template
+	return marshalWithExtra(plain(t), t.Extra)
+}
+
+// Path ...
+func (t Template) Path(method string) string {
+	var path string
+
+	if method == "POST" {
+		path = "templates"
+	} else {
+		path = "templates/" + t.Name
+	}
+	return path
+}
+
+// Unmarshal ...
+func (t *Template) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, &t)
+	return err
+}
+
+// JSON ...
+func (t Template) JSON() []byte {
+	t.ID = ""
+	t.Etag = ""
+	t.Created = ""
+	t.Updated = ""
+
+	jbytes, _ := json.Marshal(t)
+	return jbytes
+}
+
+// PatchJSON ...
+func (t Template) PatchJSON() []byte {
+	t.ID = ""
+	t.Etag = ""
+	t.Created = ""
+	t.Updated = ""
+	// removing name because it has been setup as the id param in drudapi and cannot be  patched
+	t.Name = ""
+
+	jbytes, _ := json.Marshal(t)
+	return jbytes
+}
+
+// ETAG ...
+func (t Template) ETAG() string {
+	return t.Etag
+}
+
+// Validate checks the template's name can be used as an id
+func (t Template) Validate(method string) error {
+	v := &validation{}
+	if method == "POST" {
+		v.required("name", t.Name)
+	}
+	if t.Name != "" && !IsDNSLabel(t.Name) {
+		v.add("name", "%q must be lowercase letters, numbers and hyphens", t.Name)
+	}
+	return v.err()
+}
+
+// TemplateList ...
+type TemplateList struct {
+	Items []Template `json:"_items"`
+	Meta  struct {
+		MaxResults int `json:"max_results"`
+		Page       int `json:"page"`
+		Total      int `json:"total"`
+	} `json:"_meta"`
+}
+
+// Path ...
+func (t TemplateList) Path(method string) string {
+	return "templates"
+}
+
+// Unmarshal ...
+func (t *TemplateList) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, &t)
+	return err
+}
+
+// Describe pretty prints the template list
+func (t *TemplateList) Describe() {
+	fmt.Printf("%v %v found.\n", len(t.Items), FormatPlural(len(t.Items), "template", "templates"))
+	tabWriter := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	defer tabWriter.Flush()
+	if len(t.Items) > 0 {
+		fmt.Fprintln(tabWriter, "\nNAME\tIMAGE\tDESCRIPTION")
+		for _, v := range t.Items {
+			fmt.Fprintf(tabWriter, "%v\t%v\t%v\n", v.Name, v.Image, v.Description)
+		}
+	}
+}
+
+// AllTemplates gets every page of templates
+func (r *Request) AllTemplates() ([]Template, error) {
+	var templates []Template
+	err := r.eachPage(TemplateList{}.Path("GET"), "", func(data []byte) error {
+		list := &TemplateList{}
+		err := list.Unmarshal(data)
+		templates = append(templates, list.Items...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// CheckTemplates returns a ValidationError for each deploy whose template does not exist
+func (r *Request) CheckTemplates(deploys ...Deploy) error {
+	known, err := r.templateNames()
+	if err != nil {
+		return err
+	}
+	return templateErrors(known, deploys, 0)
+}
+
+// templateNames returns the name of every template
+func (r *Request) templateNames() (map[string]bool, error) {
+	templates, err := r.AllTemplates()
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, t := range templates {
+		known[t.Name] = true
+	}
+	return known, nil
+}
+
+// templateErrors returns a ValidationError for each deploy whose template is not known.
+// Deploys are numbered from first so errors name their place in the application.
+func templateErrors(known map[string]bool, deploys []Deploy, first int) error {
+	v := &validation{}
+	for i, d := range deploys {
+		if d.Template != "" && !known[d.Template] {
+			v.add(fmt.Sprintf("deploys[%d].template", first+i), "%q is not a known template", d.Template)
+		}
+	}
+	return v.err()
+}
+
+// newDeploys returns the deploys of an application that is about to be posted, they
+// are all new. Other entities have none.
+func newDeploys(entity interface{}) []Deploy {
+	var deploys []Deploy
+	switch app := entity.(type) {
+	case *Application:
+		deploys = app.Deploys
+	case Application:
+		deploys = app.Deploys
+	}
+
+	for _, d := range deploys {
+		if d.Template != "" {
+			return deploys
+		}
+	}
+	return nil
+}
+
+// AddDeploy adds d to the application after checking its template exists
+func (r *Request) AddDeploy(app *Application, d Deploy) error {
+	if app.GetDeploy(d.Name) != nil {
+		return fmt.Errorf("Deploy %s already exists", d.Name)
+	}
+
+	if d.Template != "" {
+		known, err := r.templateNames()
+		if err != nil {
+			return err
+		}
+		err = templateErrors(known, []Deploy{d}, len(app.Deploys))
+		if err != nil {
+			return err
+		}
+	}
+
+	app.Deploys = append(app.Deploys, d)
+	err := r.Patch(app)
+	if err != nil {
+		app.Deploys = app.Deploys[:len(app.Deploys)-1]
+		return err
+	}
+	return nil
+}
+
+// TemplateDeploy identifies a deploy that uses a template
+type TemplateDeploy struct {
+	AppID  string
+	Deploy string
+}
+
+// TemplateUsage maps each template name to the deploys that use it. Templates no
+// deploy uses are included with no deploys.
+func (r *Request) TemplateUsage() (map[string][]TemplateDeploy, error) {
+	templates, err := r.AllTemplates()
+	if err != nil {
+		return nil, err
+	}
+	apps, err := r.AllApplications()
+	if err != nil {
+		return nil, err
+	}
+
+	usage := make(map[string][]TemplateDeploy)
+	for _, t := range templates {
+		usage[t.Name] = nil
+	}
+	for _, app := range apps {
+		for _, d := range app.Deploys {
+			if d.Template == "" {
+				continue
+			}
+			usage[d.Template] = append(usage[d.Template], TemplateDeploy{
+				AppID:  app.AppID,
+				Deploy: d.Name,
+			})
+		}
+	}
+
+	for _, deploys := range usage {
+		sort.Slice(deploys, func(i, j int) bool {
+			if deploys[i].AppID != deploys[j].AppID {
+				return deploys[i].AppID < deploys[j].AppID
+			}
+			return deploys[i].Deploy < deploys[j].Deploy
+		})
+	}
+	return usage, nil
+}
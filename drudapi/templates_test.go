@@ -0,0 +1,138 @@
+package drudapi
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func templatesTestServer(t *testing.T, patches *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/templates":
+			fmt.Fprint(w, `{"_items": [{"name": "wordpress"}, {"name": "drupal"}, {"name": "static"}], "_meta": {"total": 3}}`)
+		case "/application":
+			fmt.Fprint(w, `{"_items": [
+				{"app_id": "one", "deploys": [{"name": "production", "template": "wordpress"}, {"name": "staging", "template": "wordpress"}]},
+				{"app_id": "two", "deploys": [{"name": "default", "template": "drupal"}, {"name": "legacy", "template": "joomla"}]}
+			], "_meta": {"total": 2}}`)
+		case "/application/one":
+			*patches++
+			fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+}
+
+func TestGetTemplate(t *testing.T) {
+	server := getTestServer(200, `{"name": "wordpress", "image": "drud/nginx-php-fpm", "_etag": "etag1"}`)
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	tpl := &Template{Name: "wordpress"}
+	err := r.Get(tpl)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, tpl.Image, "drud/nginx-php-fpm")
+	expect(t, tpl.Path("GET"), "templates/wordpress")
+	expect(t, tpl.Path("POST"), "templates")
+}
+
+func TestAddDeployChecksTemplate(t *testing.T) {
+	patches := 0
+	server := templatesTestServer(t, &patches)
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	app := &Application{AppID: "one", Deploys: []Deploy{{Name: "production", Template: "wordpress"}}}
+
+	err := r.AddDeploy(app, Deploy{Name: "staging", Template: "joomla"})
+	verr, ok := err.(ValidationError)
+	expect(t, ok, true)
+	expect(t, verr[0].Field, "deploys[1].template")
+	expect(t, patches, 0)
+	expect(t, len(app.Deploys), 1)
+
+	err = r.AddDeploy(app, Deploy{Name: "staging", Template: "drupal"})
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, patches, 1)
+	expect(t, len(app.Deploys), 2)
+	expect(t, app.Etag, "etag2")
+}
+
+func TestPostChecksTemplates(t *testing.T) {
+	patches := 0
+	server := templatesTestServer(t, &patches)
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	// application posts fail before reaching the server
+	app := &Application{
+		Name:    "site",
+		Client:  Client{Name: "client"},
+		Deploys: []Deploy{{Name: "production", Template: "joomla"}},
+	}
+	err := r.Post(app)
+	_, ok := err.(ValidationError)
+	expect(t, ok, true)
+}
+
+func TestPatchIgnoresRetiredTemplates(t *testing.T) {
+	patches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.URL.Path, "/application/one")
+		patches++
+		fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	// an unrelated change to an app whose deploy uses a retired template
+	app := &Application{AppID: "one", Deploys: []Deploy{{Name: "production", Template: "legacy"}}}
+	app.SlackChannel = "#ops"
+	err := r.Patch(app)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, patches, 1)
+}
+
+func TestTemplateUsage(t *testing.T) {
+	patches := 0
+	server := templatesTestServer(t, &patches)
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	usage, err := r.TemplateUsage()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, len(usage), 4)
+	expect(t, len(usage["wordpress"]), 2)
+	expect(t, usage["wordpress"][0].Deploy, "production")
+	expect(t, usage["drupal"][0].AppID, "two")
+	expect(t, len(usage["static"]), 0)
+	expect(t, usage["joomla"][0].Deploy, "legacy")
+}
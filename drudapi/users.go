@@ -1,22 +1,258 @@
 package drudapi
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"text/tabwriter"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
+// PasswordCost is the bcrypt cost used when hashing user passwords
+var PasswordCost = bcrypt.DefaultCost
+
+// TokenLength is the number of random bytes used to generate a user's auth_token
+var TokenLength = 20
+
+// ErrPasswordMismatch is returned when a password does not match a user's Hashpw
+var ErrPasswordMismatch = errors.New("password does not match")
+
 // User represents a user entity from the api
 type User struct {
 	Username string      `json:"username"`
-	Hashpw   string      `json:"hashpw"`
+	Hashpw   string      `json:"hashpw,omitempty"`
 	Token    string      `json:"auth_token,omitempty"`
 	Created  string      `json:"_created,omitempty"`
 	Etag     string      `json:"_etag,omitempty"`
 	ID       string      `json:"_id,omitempty"`
 	Updated  string      `json:"_updated,omitempty"`
 	Auth     Credentials `json:"-"`
+	// Extra holds fields returned by the api that User does not know about
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// UnmarshalJSON decodes the User and keeps any fields it does not know about in Extra
+func (u *User) UnmarshalJSON(data []byte) error {
+	type plain User
+	return unmarshalWithExtra(data, (*plain)(u), &u.Extra)
+}
+
+// MarshalJSON encodes the User along with the fields kept in Extra
+func (u User) MarshalJSON() ([]byte, error) {
+	type plain User
+	return marshalWithExtra(plain(u), u.Extra)
+}
+
+// NewUser returns a user whose Hashpw is the bcrypt hash of password
+func NewUser(username string, password string) (*User, error) {
+	u := &User{
+		Username: username,
+	}
+
+	err := u.SetPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SetPassword hashes password and stores the result in Hashpw
+func (u *User) SetPassword(password string) error {
+	if password == "" {
+		return errors.New("password cannot be empty")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), PasswordCost)
+	if err != nil {
+		return err
+	}
+
+	u.Hashpw = string(hash)
+	return nil
+}
+
+// CheckPassword returns ErrPasswordMismatch if password does not match Hashpw
+func (u *User) CheckPassword(password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(u.Hashpw), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+// String returns the user's json with the password hash and token masked so
+// users can be logged safely
+func (u User) String() string {
+	if u.Hashpw != "" {
+		u.Hashpw = "********"
+	}
+	if u.Token != "" {
+		u.Token = "********"
+	}
+
+	jbytes, _ := json.Marshal(u)
+	return string(jbytes)
+}
+
+// secretFields are json keys whose values are masked by redact and never cached
+var secretFields = map[string]bool{
+	"hashpw":           true,
+	"auth_token":       true,
+	"admin_token":      true,
+	"basicauth_pass":   true,
+	"auth_key":         true,
+	"secure_auth_key":  true,
+	"logged_in_key":    true,
+	"nonce_key":        true,
+	"auth_salt":        true,
+	"secure_auth_salt": true,
+	"logged_in_salt":   true,
+	"nonce_salt":       true,
+}
+
+// redact returns body with the values of any secretFields masked so api
+// responses can be logged safely. Bodies that are not json are returned as is.
+func redact(body []byte) string {
+	var doc interface{}
+	if json.Unmarshal(body, &doc) != nil {
+		return string(body)
+	}
+
+	jbytes, err := json.Marshal(redactValue(doc))
+	if err != nil {
+		return string(body)
+	}
+	return string(jbytes)
+}
+
+// redactValue masks secretFields in every object nested in v
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if secretFields[key] {
+				v[key] = "********"
+				continue
+			}
+			v[key] = redactValue(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = redactValue(value)
+		}
+	}
+	return v
+}
+
+// stripSecrets removes secretFields from every object nested in v and reports
+// whether there were any
+func stripSecrets(v interface{}) bool {
+	found := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if secretFields[key] {
+				delete(v, key)
+				found = true
+				continue
+			}
+			if stripSecrets(value) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if stripSecrets(value) {
+				found = true
+			}
+		}
+	}
+	return found
+}
+
+// CreateUser posts a new user with a bcrypt hashed password
+func (r *Request) CreateUser(username string, password string) (*User, error) {
+	u, err := NewUser(username, password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Post(u)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// ChangePassword verifies oldPassword against the user's current hash before
+// patching the user with a hash of newPassword
+func (r *Request) ChangePassword(u *User, oldPassword string, newPassword string) error {
+	err := r.Get(u)
+	if err != nil {
+		return err
+	}
+
+	err = u.CheckPassword(oldPassword)
+	if err != nil {
+		return err
+	}
+
+	patch := &User{
+		Username: u.Username,
+		Etag:     u.Etag,
+	}
+	err = patch.SetPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	err = r.Patch(patch)
+	if err != nil {
+		return err
+	}
+
+	u.Hashpw = patch.Hashpw
+	u.Etag = patch.Etag
+	u.Updated = patch.Updated
+	return nil
+}
+
+// RotateToken generates a new auth_token for the user and patches it to the api
+func (r *Request) RotateToken(u *User) error {
+	token, err := newToken()
+	if err != nil {
+		return err
+	}
+
+	patch := &User{
+		Username: u.Username,
+		Token:    token,
+		Etag:     u.Etag,
+	}
+
+	err = r.Patch(patch)
+	if err != nil {
+		return err
+	}
+
+	u.Token = token
+	u.Etag = patch.Etag
+	u.Updated = patch.Updated
+	return nil
+}
+
+// newToken returns a random hex encoded token
+func newToken() (string, error) {
+	b := make([]byte, TokenLength)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
 }
 
 // Path ...
@@ -64,6 +300,16 @@ func (u User) ETAG() string {
 	return u.Etag
 }
 
+// Validate checks the user's required fields
+func (u User) Validate(method string) error {
+	v := &validation{}
+	v.required("username", u.Username)
+	if method == "POST" {
+		v.required("hashpw", u.Hashpw)
+	}
+	return v.err()
+}
+
 // UserList entity
 type UserList struct {
 	Items []User `json:"_items"`
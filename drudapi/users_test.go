@@ -0,0 +1,164 @@
+package drudapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	PasswordCost = bcrypt.MinCost
+}
+
+func TestNewUser(t *testing.T) {
+	u, err := NewUser("fred", "hunter2")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, u.Username, "fred")
+	refute(t, u.Hashpw, "hunter2")
+	expect(t, u.CheckPassword("hunter2"), nil)
+	expect(t, u.CheckPassword("hunter3"), ErrPasswordMismatch)
+
+	_, err = NewUser("fred", "")
+	refute(t, err, nil)
+}
+
+func TestUserStringMasksSecrets(t *testing.T) {
+	u, err := NewUser("fred", "hunter2")
+	if err != nil {
+		log.Fatal(err)
+	}
+	u.Token = "supersecrettoken"
+
+	out := fmt.Sprintf("%v", u)
+	expect(t, strings.Contains(out, u.Hashpw), false)
+	expect(t, strings.Contains(out, u.Token), false)
+	expect(t, strings.Contains(out, "fred"), true)
+}
+
+func TestChangePassword(t *testing.T) {
+	u, err := NewUser("fred", "hunter2")
+	if err != nil {
+		log.Fatal(err)
+	}
+	u.Etag = "etag1"
+
+	var patched User
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.URL.Path, "/users/fred")
+		switch r.Method {
+		case "GET":
+			w.Write(u.JSON())
+		case "PATCH":
+			expect(t, r.Header.Get("If-Match"), "etag1")
+			body, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &patched)
+			fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+		}
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+		Auth: &Credentials{
+			AdminToken: "dfgdfg",
+		},
+	}
+
+	target := &User{Username: "fred", Etag: "etag1"}
+	err = r.ChangePassword(target, "wrong", "newpass")
+	expect(t, err, ErrPasswordMismatch)
+
+	err = r.ChangePassword(target, "hunter2", "newpass")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, patched.Hashpw, target.Hashpw)
+	expect(t, patched.Token, "")
+	expect(t, target.Etag, "etag2")
+	expect(t, target.CheckPassword("newpass"), nil)
+}
+
+func TestRotateToken(t *testing.T) {
+	var patched User
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.Method, "PATCH")
+		expect(t, r.URL.Path, "/users/fred")
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &patched)
+		fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+		Auth: &Credentials{
+			AdminToken: "dfgdfg",
+		},
+	}
+
+	u := &User{Username: "fred", Token: "oldtoken", Etag: "etag1"}
+	err := r.RotateToken(u)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	refute(t, u.Token, "oldtoken")
+	expect(t, len(u.Token), TokenLength*2)
+	expect(t, patched.Token, u.Token)
+	expect(t, patched.Hashpw, "")
+	expect(t, u.Etag, "etag2")
+}
+
+func TestRejectedUserIsLoggedRedacted(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		// echo the rejected document back as a misbehaving api might
+		fmt.Fprintf(w, `{"_status": "ERR", "_issues": {"username": "not unique"}, "_document": %s}`, body)
+	}))
+	defer server.Close()
+
+	var logged bytes.Buffer
+	log.SetOutput(&logged)
+	defer log.SetOutput(os.Stderr)
+
+	r := Request{
+		Host: server.URL,
+		Auth: &Credentials{
+			AdminToken: "dfgdfg",
+		},
+	}
+
+	u, err := NewUser("fred", "hunter2")
+	if err != nil {
+		log.Fatal(err)
+	}
+	u.Token = "supersecrettoken"
+
+	err = r.Post(u)
+	expect(t, IsStatus(err, http.StatusUnprocessableEntity), true)
+
+	out := logged.String()
+	expect(t, strings.Contains(out, "not unique"), true)
+	expect(t, strings.Contains(out, u.Hashpw), false)
+	expect(t, strings.Contains(out, u.Token), false)
+
+	logged.Reset()
+	_, err = r.PostAll([]Entity{u})
+	refute(t, err, nil)
+	expect(t, strings.Contains(logged.String(), u.Hashpw), false)
+	expect(t, strings.Contains(logged.String(), u.Token), false)
+}
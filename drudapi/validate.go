@@ -0,0 +1,110 @@
+package drudapi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Validator is implemented by entities that can check their fields before being
+// sent to the api. method is the http method the entity is about to be sent with,
+// required fields are only enforced for POST.
+type Validator interface {
+	Validate(method string) error
+}
+
+// FieldError describes a problem with a single field of an entity
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// ValidationError lists every field of an entity that failed validation
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	var msgs []string
+	for _, f := range e {
+		msgs = append(msgs, f.Error())
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// validation collects field errors while an entity is being validated
+type validation struct {
+	errs ValidationError
+}
+
+// add records a field error
+func (v *validation) add(field string, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{
+		Field:   field,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// required records an error if value is empty
+func (v *validation) required(field string, value string) {
+	if value == "" {
+		v.add(field, "is required")
+	}
+}
+
+// merge adds the errors of a nested entity's validation with field as their prefix
+func (v *validation) merge(field string, err error) {
+	if err == nil {
+		return
+	}
+	if nested, ok := err.(ValidationError); ok {
+		for _, f := range nested {
+			v.errs = append(v.errs, FieldError{
+				Field:   field + "." + f.Field,
+				Message: f.Message,
+			})
+		}
+		return
+	}
+	v.add(field, "%s", err)
+}
+
+// err returns nil when no field errors were recorded
+func (v *validation) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// validate runs entity's validation if it has one
+func validate(entity interface{}, method string) error {
+	if v, ok := entity.(Validator); ok {
+		return v.Validate(method)
+	}
+	return nil
+}
+
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// IsDNSLabel checks that name can be used as a single label of a hostname
+func IsDNSLabel(name string) bool {
+	return dnsLabel.MatchString(name)
+}
+
+// IsHostname checks that name is a syntactically valid hostname
+func IsHostname(name string) bool {
+	name = strings.TrimSuffix(strings.ToLower(name), ".")
+	if name == "" || len(name) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if !IsDNSLabel(label) {
+			return false
+		}
+	}
+	return true
+}
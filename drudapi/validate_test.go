@@ -0,0 +1,89 @@
+package drudapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeployValidate(t *testing.T) {
+	expect(t, Deploy{Name: "default", Protocol: "https", Hostname: "www.example.com"}.Validate("PATCH"), nil)
+
+	err := Deploy{Name: "Not_DNS", Protocol: "ftp", Hostname: "bad..host"}.Validate("PATCH")
+	verr, ok := err.(ValidationError)
+	expect(t, ok, true)
+	expect(t, len(verr), 3)
+	expect(t, verr[0].Field, "name")
+	expect(t, verr[1].Field, "protocol")
+	expect(t, verr[2].Field, "hostname")
+}
+
+func TestApplicationValidate(t *testing.T) {
+	app := Application{
+		Deploys: []Deploy{
+			{Name: "default"},
+			{Name: "default", Protocol: "gopher"},
+		},
+	}
+
+	verr := app.Validate("POST").(ValidationError)
+	var fields []string
+	for _, f := range verr {
+		fields = append(fields, f.Field)
+	}
+	expect(t, len(fields), 4)
+	expect(t, fields[0], "name")
+	expect(t, fields[1], "client.name")
+	expect(t, fields[2], "deploys[1].protocol")
+	expect(t, fields[3], "deploys[1].name")
+
+	// required fields are only enforced when creating
+	app.Deploys = app.Deploys[:1]
+	expect(t, app.Validate("PATCH"), nil)
+}
+
+func TestApplicationValidateClientReference(t *testing.T) {
+	var app Application
+	err := json.Unmarshal([]byte(`{"name": "site", "client": "5750b4d4e2638a001f2c9b2e"}`), &app)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	expect(t, app.Client.IsReference(), true)
+	expect(t, app.Validate("POST"), nil)
+}
+
+func TestHostnames(t *testing.T) {
+	expect(t, IsHostname("example.com"), true)
+	expect(t, IsHostname("a-b.example.com."), true)
+	expect(t, IsHostname("-a.example.com"), false)
+	expect(t, IsHostname("a_b.example.com"), false)
+	expect(t, IsHostname(""), false)
+}
+
+func TestPostValidatesBeforeSending(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Invalid entity should not be sent")
+	}))
+	defer server.Close()
+
+	r := Request{
+		Host: server.URL,
+	}
+
+	err := r.Post(&Build{DeployName: "Bad Name"})
+	verr, ok := err.(ValidationError)
+	expect(t, ok, true)
+	expect(t, len(verr), 2)
+
+	err = r.Patch(&Application{Deploys: []Deploy{{Name: "ok", Protocol: "ftp"}}})
+	_, ok = err.(ValidationError)
+	expect(t, ok, true)
+
+	results, err := r.PostAll([]Entity{&Client{Name: "fine"}, &Client{}})
+	refute(t, err, nil)
+	expect(t, results[0].Err, nil)
+	refute(t, results[1].Err, nil)
+}
@@ -0,0 +1,309 @@
+// Package exporter serves prometheus metrics about DRUD builds, applications and deploys
+// gathered from the drudapi.
+package exporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/drud/drud-go/drudapi"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+const namespace = "drud"
+
+// Exporter periodically gathers build and application state from the api and exposes it
+// as prometheus metrics
+type Exporter struct {
+	Request  *drudapi.Request
+	Interval time.Duration // how often Run refreshes the metrics
+	Window   time.Duration // how far back builds are counted
+
+	registry       *prometheus.Registry
+	builds         *prometheus.GaugeVec
+	buildsFinished *prometheus.CounterVec
+	buildDuration  *prometheus.HistogramVec
+	applications   *prometheus.GaugeVec
+	deploys        *prometheus.GaugeVec
+	apiLatency     *prometheus.HistogramVec
+	lastRefresh    prometheus.Gauge
+	refreshErrors  prometheus.Counter
+
+	lock     sync.Mutex
+	finished map[string]time.Time // builds already counted as finished, by _id
+}
+
+// New returns an exporter for the api r talks to. Requests made by the exporter are timed
+// as the api latency.
+func New(r *drudapi.Request) *Exporter {
+	e := &Exporter{
+		Interval: time.Minute,
+		Window:   24 * time.Hour,
+		registry: prometheus.NewRegistry(),
+		finished: make(map[string]time.Time),
+		builds: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "builds",
+			Help:      "Builds created within the window by application, deploy and state.",
+		}, []string{"app", "deploy", "state"}),
+		buildsFinished: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "builds_finished_total",
+			Help:      "Builds seen finishing by application, deploy and state.",
+		}, []string{"app", "deploy", "state"}),
+		buildDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "build_duration_seconds",
+			Help:      "Time from a build being created to it finishing.",
+			Buckets:   prometheus.ExponentialBuckets(15, 2, 8),
+		}, []string{"app", "deploy", "state"}),
+		applications: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "applications",
+			Help:      "Applications by client.",
+		}, []string{"client"}),
+		deploys: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "deploys",
+			Help:      "Deploys by client.",
+		}, []string{"client"}),
+		apiLatency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "api_request_duration_seconds",
+			Help:      "Latency of requests made to the drudapi by method, resource and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "resource", "code"}),
+		lastRefresh: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "exporter_last_refresh_timestamp_seconds",
+			Help:      "When the metrics were last refreshed successfully.",
+		}),
+		refreshErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "exporter_refresh_errors_total",
+			Help:      "Refreshes that failed.",
+		}),
+	}
+
+	e.registry.MustRegister(
+		e.builds,
+		e.buildsFinished,
+		e.buildDuration,
+		e.applications,
+		e.deploys,
+		e.apiLatency,
+		e.lastRefresh,
+		e.refreshErrors,
+	)
+
+	q := *r
+	q.Client = &http.Client{
+		Transport: &timedTransport{
+			base:     http.DefaultTransport,
+			basePath: basePath(r.Host),
+			latency:  e.apiLatency,
+		},
+	}
+	e.Request = &q
+	return e
+}
+
+// basePath is the path of the api host that resource paths are relative to
+func basePath(host string) string {
+	u, err := url.Parse(host)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(u.Path, "/")
+}
+
+// timedTransport records the latency of each request it sends
+type timedTransport struct {
+	base     http.RoundTripper
+	basePath string
+	latency  *prometheus.HistogramVec
+}
+
+// RoundTrip ...
+func (t *timedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = fmt.Sprint(resp.StatusCode)
+	}
+	resource := strings.TrimPrefix(strings.TrimPrefix(req.URL.Path, t.basePath), "/")
+	resource = strings.SplitN(resource, "/", 2)[0]
+
+	t.latency.WithLabelValues(req.Method, resource, code).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// Refresh gathers the applications and the builds created within the window and updates
+// the metrics
+func (e *Exporter) Refresh() error {
+	err := e.refresh()
+	if err != nil {
+		e.refreshErrors.Inc()
+		return err
+	}
+	e.lastRefresh.Set(float64(time.Now().Unix()))
+	return nil
+}
+
+func (e *Exporter) refresh() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	apps, err := e.Request.AllApplications()
+	if err != nil {
+		return err
+	}
+	clients, err := e.Request.AllClients()
+	if err != nil {
+		return err
+	}
+	names := drudapi.ClientNamesByID(clients)
+
+	since := time.Now().Add(-e.Window)
+	builds, err := e.recentBuilds(since)
+	if err != nil {
+		return err
+	}
+
+	e.applications.Reset()
+	e.deploys.Reset()
+	appIDs := make(map[string]string)
+	for _, app := range apps {
+		appIDs[app.ID] = app.AppID
+		client := app.ClientName(names)
+		e.applications.WithLabelValues(client).Inc()
+		e.deploys.WithLabelValues(client).Add(float64(len(app.Deploys)))
+	}
+
+	e.builds.Reset()
+	for _, b := range builds {
+		app := appIDs[b.Application.ID]
+		if app == "" {
+			app = b.Application.AppID
+		}
+		if app == "" {
+			app = b.Name
+		}
+		e.builds.WithLabelValues(app, b.DeployName, b.State).Inc()
+
+		if !b.Finished() {
+			continue
+		}
+		if _, ok := e.finished[b.ID]; ok {
+			continue
+		}
+		created, err := drudapi.ParseTime(b.Created)
+		if err != nil {
+			continue
+		}
+		e.finished[b.ID] = created
+		e.buildsFinished.WithLabelValues(app, b.DeployName, b.State).Inc()
+
+		updated, err := drudapi.ParseTime(b.Updated)
+		if err == nil && updated.After(created) {
+			e.buildDuration.WithLabelValues(app, b.DeployName, b.State).Observe(updated.Sub(created).Seconds())
+		}
+	}
+
+	// builds created before the window are never listed again
+	for id, created := range e.finished {
+		if created.Before(since) {
+			delete(e.finished, id)
+		}
+	}
+	return nil
+}
+
+// recentBuilds gets every build created since the given time
+func (e *Exporter) recentBuilds(since time.Time) ([]drudapi.Build, error) {
+	where, err := json.Marshal(map[string]interface{}{
+		"_created": map[string]interface{}{"$gte": since.UTC().Format(time.RFC1123)},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var builds []drudapi.Build
+	for page := 1; ; page++ {
+		q := *e.Request
+		q.Query = fmt.Sprintf("where=%s&sort=-_created&page=%d&max_results=%d", url.QueryEscape(string(where)), page, drudapi.PageSize)
+
+		list := &drudapi.BuildList{}
+		err = q.Get(list)
+		if err != nil {
+			return nil, err
+		}
+
+		builds = append(builds, list.Items...)
+		if len(list.Items) == 0 || len(builds) >= list.Meta.Total {
+			return builds, nil
+		}
+	}
+}
+
+// Run refreshes the metrics every Interval until stop is closed
+func (e *Exporter) Run(stop <-chan struct{}) {
+	ticker := time.NewTicker(e.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := e.Refresh()
+		if err != nil {
+			log.Printf("Could not refresh metrics: %s", err)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP writes the metrics in the prometheus text format
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	families, err := e.registry.Gather()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.FmtText)
+	for _, family := range families {
+		err = enc.Encode(family)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", string(expfmt.FmtText))
+	w.Write(buf.Bytes())
+}
+
+// ListenAndServe refreshes the metrics in the background and serves them at /metrics on addr
+func (e *Exporter) ListenAndServe(addr string) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go e.Run(stop)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", e)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,123 @@
+package exporter
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/drud/drud-go/drudapi"
+)
+
+func apiTestServer(t *testing.T, builds *string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v0.1/application":
+			fmt.Fprint(w, `{"_items": [
+				{"_id": "a1", "app_id": "one", "client": "c1", "deploys": [{"name": "production"}, {"name": "staging"}]},
+				{"_id": "a2", "app_id": "two", "client": "c1", "deploys": [{"name": "production"}]},
+				{"_id": "a3", "app_id": "three", "client": {"name": "globex"}}
+			], "_meta": {"total": 3}}`)
+		case "/v0.1/client":
+			fmt.Fprint(w, `{"_items": [{"_id": "c1", "name": "acme"}, {"_id": "c2", "name": "globex"}], "_meta": {"total": 2}}`)
+		case "/v0.1/builds":
+			if !strings.Contains(r.URL.Query().Get("where"), "_created") {
+				t.Errorf("Builds were not limited to the window: %s", r.URL.RawQuery)
+			}
+			fmt.Fprint(w, *builds)
+		default:
+			t.Errorf("Unexpected request %s", r.URL.Path)
+		}
+	}))
+}
+
+func scrape(t *testing.T, e *Exporter) string {
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func expectMetric(t *testing.T, metrics string, line string) {
+	if !strings.Contains(metrics, line+"\n") {
+		t.Errorf("Expected %q in metrics:\n%s", line, metrics)
+	}
+}
+
+func TestExporterRefresh(t *testing.T) {
+	now := time.Now().UTC()
+	created := now.Add(-10 * time.Minute).Format(time.RFC1123)
+	finished := now.Add(-8 * time.Minute).Format(time.RFC1123)
+
+	builds := fmt.Sprintf(`{"_items": [
+		{"_id": "b1", "application": "a1", "deploy_name": "production", "state": "success", "_created": "%[1]s", "_updated": "%[2]s"},
+		{"_id": "b2", "application": "a1", "deploy_name": "production", "state": "failed", "_created": "%[1]s", "_updated": "%[2]s"},
+		{"_id": "b3", "application": "a2", "deploy_name": "production", "state": "building", "_created": "%[1]s", "_updated": "%[1]s"}
+	], "_meta": {"total": 3}}`, created, finished)
+
+	server := apiTestServer(t, &builds)
+	defer server.Close()
+
+	e := New(&drudapi.Request{Host: server.URL + "/v0.1"})
+	err := e.Refresh()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := scrape(t, e)
+	expectMetric(t, metrics, `drud_applications{client="acme"} 2`)
+	expectMetric(t, metrics, `drud_applications{client="globex"} 1`)
+	expectMetric(t, metrics, `drud_deploys{client="acme"} 3`)
+	expectMetric(t, metrics, `drud_builds{app="one",deploy="production",state="success"} 1`)
+	expectMetric(t, metrics, `drud_builds{app="two",deploy="production",state="building"} 1`)
+	expectMetric(t, metrics, `drud_builds_finished_total{app="one",deploy="production",state="failed"} 1`)
+	expectMetric(t, metrics, `drud_build_duration_seconds_sum{app="one",deploy="production",state="success"} 120`)
+	expectMetric(t, metrics, `drud_api_request_duration_seconds_count{code="200",method="GET",resource="builds"} 1`)
+
+	// the running build finishing is counted once however many refreshes see it
+	builds = strings.Replace(builds, `"state": "building", "_created": "`+created+`", "_updated": "`+created+`"`,
+		`"state": "success", "_created": "`+created+`", "_updated": "`+finished+`"`, 1)
+	for i := 0; i < 2; i++ {
+		err = e.Refresh()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	metrics = scrape(t, e)
+	expectMetric(t, metrics, `drud_builds_finished_total{app="one",deploy="production",state="success"} 1`)
+	expectMetric(t, metrics, `drud_builds_finished_total{app="two",deploy="production",state="success"} 1`)
+	expectMetric(t, metrics, `drud_api_request_duration_seconds_count{code="200",method="GET",resource="application"} 3`)
+	if strings.Contains(metrics, `state="building"`) {
+		t.Errorf("Stale build state still exported:\n%s", metrics)
+	}
+}
+
+func TestExporterRefreshError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	e := New(&drudapi.Request{Host: server.URL})
+	err := e.Refresh()
+	if err == nil {
+		t.Fatal("Expected refresh to fail")
+	}
+
+	metrics := scrape(t, e)
+	expectMetric(t, metrics, `drud_exporter_refresh_errors_total 1`)
+	expectMetric(t, metrics, `drud_api_request_duration_seconds_count{code="500",method="GET",resource="application"} 1`)
+}
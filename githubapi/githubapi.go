@@ -0,0 +1,272 @@
+// Package githubapi verifies the GitHub repositories of DRUD applications and manages
+// the push webhooks that trigger their builds.
+package githubapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/drud/drud-go/drudapi"
+	"github.com/google/go-github/github"
+)
+
+// HookEvents are the events the build webhook is registered for
+var HookEvents = []string{"push"}
+
+// Client talks to the GitHub api on behalf of DRUD applications
+type Client struct {
+	GH *github.Client
+}
+
+// tokenTransport authenticates each request with a personal access token
+type tokenTransport struct {
+	token string
+	base  http.RoundTripper
+}
+
+// RoundTrip ...
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request they are given
+	authed := new(http.Request)
+	*authed = *req
+	authed.Header = make(http.Header)
+	for k, v := range req.Header {
+		authed.Header[k] = v
+	}
+	authed.Header.Set("Authorization", "token "+t.token)
+	return t.base.RoundTrip(authed)
+}
+
+// NewClient returns a client for api.github.com authenticated with token. An empty token
+// makes unauthenticated requests.
+func NewClient(token string) *Client {
+	httpClient := &http.Client{}
+	if token != "" {
+		httpClient.Transport = &tokenTransport{token: token, base: http.DefaultTransport}
+	}
+	return &Client{
+		GH: github.NewClient(httpClient),
+	}
+}
+
+// SetBaseURL points the client at another api such as GitHub Enterprise or a test server
+func (c *Client) SetBaseURL(baseURL string) error {
+	if !strings.HasSuffix(baseURL, "/") {
+		baseURL += "/"
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	c.GH.BaseURL = u
+	return nil
+}
+
+// Repo returns the owner and name of the application's repository from its RepoDetails,
+// falling back to RepoOrg and Repo
+func Repo(app *drudapi.Application) (string, string, error) {
+	if app.RepoDetails != nil && app.RepoDetails.Org != "" && app.RepoDetails.Name != "" {
+		return app.RepoDetails.Org, app.RepoDetails.Name, nil
+	}
+	if app.RepoOrg != "" && app.Repo != "" {
+		return app.RepoOrg, app.Repo, nil
+	}
+	return "", "", fmt.Errorf("Application %s has no github repo", app.AppID)
+}
+
+// isNotFound reports whether err is a 404 from GitHub
+func isNotFound(err error) bool {
+	errResp, ok := err.(*github.ErrorResponse)
+	return ok && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound
+}
+
+// CheckRepo verifies the application's repository exists along with the branch in its
+// RepoDetails and the branch of each of its deploys
+func (c *Client) CheckRepo(app *drudapi.Application) error {
+	owner, name, err := Repo(app)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = c.GH.Repositories.Get(owner, name)
+	if isNotFound(err) {
+		return fmt.Errorf("Repository %s/%s not found", owner, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	var branches []string
+	if app.RepoDetails != nil && app.RepoDetails.Branch != "" {
+		branches = append(branches, app.RepoDetails.Branch)
+	}
+	for _, d := range app.Deploys {
+		branches = append(branches, d.Branch)
+	}
+
+	checked := make(map[string]bool)
+	var missing []string
+	for _, branch := range branches {
+		if branch == "" || checked[branch] {
+			continue
+		}
+		checked[branch] = true
+
+		_, _, err = c.GH.Repositories.GetBranch(owner, name, branch)
+		if isNotFound(err) {
+			missing = append(missing, branch)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s %s not found in %s/%s", drudapi.FormatPlural(len(missing), "Branch", "Branches"), strings.Join(missing, ", "), owner, name)
+	}
+	return nil
+}
+
+// hookURL returns the url a hook delivers to
+func hookURL(h *github.Hook) string {
+	u, _ := h.Config["url"].(string)
+	return u
+}
+
+// sameEndpoint reports whether two hook urls deliver to the same scheme and host
+func sameEndpoint(a string, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Scheme == ub.Scheme && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// listHooks gets every hook of a repository
+func (c *Client) listHooks(owner string, name string) ([]*github.Hook, error) {
+	var hooks []*github.Hook
+	opt := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := c.GH.Repositories.ListHooks(owner, name, opt)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, page...)
+		if resp.NextPage == 0 {
+			return hooks, nil
+		}
+		opt.Page = resp.NextPage
+	}
+}
+
+// EnsureHook creates or updates the push webhook of the application's repository so it
+// delivers to target signed with secret. The hook is found by GithubHookID, or by its url
+// if that hook no longer exists, and its id is saved to the application through r.
+func (c *Client) EnsureHook(r *drudapi.Request, app *drudapi.Application, target string, secret string) (*github.Hook, error) {
+	owner, name, err := Repo(app)
+	if err != nil {
+		return nil, err
+	}
+
+	hook := &github.Hook{
+		Name:   github.String("web"),
+		Events: HookEvents,
+		Active: github.Bool(true),
+		Config: map[string]interface{}{
+			"url":          target,
+			"content_type": "json",
+			"secret":       secret,
+		},
+	}
+
+	id := app.GithubHookID
+	if id != 0 {
+		_, _, err = c.GH.Repositories.GetHook(owner, name, id)
+		if isNotFound(err) {
+			id = 0
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if id == 0 {
+		hooks, err := c.listHooks(owner, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hooks {
+			if hookURL(h) == target && h.ID != nil {
+				id = *h.ID
+				break
+			}
+		}
+	}
+
+	var saved *github.Hook
+	if id != 0 {
+		saved, _, err = c.GH.Repositories.EditHook(owner, name, id, hook)
+	} else {
+		saved, _, err = c.GH.Repositories.CreateHook(owner, name, hook)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if saved.ID != nil && *saved.ID != app.GithubHookID {
+		app.GithubHookID = *saved.ID
+		err = r.Patch(app)
+		if err != nil {
+			return saved, fmt.Errorf("Hook %d was saved on github but not on %s: %s", *saved.ID, app.AppID, err)
+		}
+	}
+	return saved, nil
+}
+
+// OrphanedHooks returns the hooks of the application's repository that deliver to the
+// same host as target but are not the hook recorded in GithubHookID, e.g. hooks left by
+// earlier registrations
+func (c *Client) OrphanedHooks(app *drudapi.Application, target string) ([]*github.Hook, error) {
+	owner, name, err := Repo(app)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := c.listHooks(owner, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []*github.Hook
+	for _, h := range hooks {
+		if h.ID != nil && *h.ID == app.GithubHookID {
+			continue
+		}
+		if sameEndpoint(hookURL(h), target) {
+			orphans = append(orphans, h)
+		}
+	}
+	return orphans, nil
+}
+
+// RemoveOrphanedHooks deletes the hooks OrphanedHooks finds and returns how many were removed
+func (c *Client) RemoveOrphanedHooks(app *drudapi.Application, target string) (int, error) {
+	orphans, err := c.OrphanedHooks(app, target)
+	if err != nil {
+		return 0, err
+	}
+
+	owner, name, _ := Repo(app)
+	for i, h := range orphans {
+		_, err = c.GH.Repositories.DeleteHook(owner, name, *h.ID)
+		if err != nil && !isNotFound(err) {
+			return i, err
+		}
+	}
+	return len(orphans), nil
+}
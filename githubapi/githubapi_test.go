@@ -0,0 +1,243 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/drud/drud-go/drudapi"
+	"github.com/google/go-github/github"
+)
+
+// fakeGithub serves the repos, branches and hooks used by this package
+type fakeGithub struct {
+	t      *testing.T
+	repos  map[string][]string // branches by owner/name
+	hooks  map[int]*github.Hook
+	nextID int
+	lock   sync.Mutex
+	token  string
+}
+
+func (f *fakeGithub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.token = r.Header.Get("Authorization")
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 3 || parts[0] != "repos" {
+		http.NotFound(w, r)
+		return
+	}
+
+	branches, ok := f.repos[parts[1]+"/"+parts[2]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Not Found"}`)
+		return
+	}
+
+	switch {
+	case len(parts) == 3:
+		fmt.Fprintf(w, `{"name": "%s", "full_name": "%s/%s"}`, parts[2], parts[1], parts[2])
+	case len(parts) == 5 && parts[3] == "branches":
+		for _, b := range branches {
+			if b == parts[4] {
+				fmt.Fprintf(w, `{"name": "%s"}`, b)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message": "Branch not found"}`)
+	case len(parts) == 4 && parts[3] == "hooks" && r.Method == "GET":
+		var ids []int
+		for id := range f.hooks {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+		var hooks []*github.Hook
+		for _, id := range ids {
+			hooks = append(hooks, f.hooks[id])
+		}
+		json.NewEncoder(w).Encode(hooks)
+	case len(parts) == 4 && parts[3] == "hooks" && r.Method == "POST":
+		hook := &github.Hook{}
+		json.NewDecoder(r.Body).Decode(hook)
+		f.nextID++
+		hook.ID = github.Int(f.nextID)
+		f.hooks[f.nextID] = hook
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hook)
+	case len(parts) == 5 && parts[3] == "hooks":
+		id, _ := strconv.Atoi(parts[4])
+		hook, ok := f.hooks[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message": "Not Found"}`)
+			return
+		}
+		switch r.Method {
+		case "PATCH":
+			json.NewDecoder(r.Body).Decode(hook)
+			hook.ID = github.Int(id)
+		case "DELETE":
+			delete(f.hooks, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		json.NewEncoder(w).Encode(hook)
+	default:
+		f.t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+	}
+}
+
+func testClient(t *testing.T, f *fakeGithub) (*Client, func()) {
+	server := httptest.NewServer(f)
+	c := NewClient("secret-token")
+	err := c.SetBaseURL(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, server.Close
+}
+
+func TestCheckRepo(t *testing.T) {
+	f := &fakeGithub{t: t, repos: map[string][]string{"drud/site": {"master", "develop"}}}
+	c, done := testClient(t, f)
+	defer done()
+
+	app := &drudapi.Application{
+		AppID:   "site",
+		RepoOrg: "drud",
+		Repo:    "site",
+		Deploys: []drudapi.Deploy{{Name: "production", Branch: "master"}, {Name: "staging", Branch: "develop"}},
+	}
+	err := c.CheckRepo(app)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.token != "token secret-token" {
+		t.Errorf("Expected token auth, got %q", f.token)
+	}
+
+	app.Deploys = append(app.Deploys, drudapi.Deploy{Name: "feature", Branch: "feature-x"})
+	err = c.CheckRepo(app)
+	if err == nil || err.Error() != "Branch feature-x not found in drud/site" {
+		t.Errorf("Expected missing branch, got %v", err)
+	}
+
+	app.Repo = "missing"
+	err = c.CheckRepo(app)
+	if err == nil || err.Error() != "Repository drud/missing not found" {
+		t.Errorf("Expected missing repo, got %v", err)
+	}
+
+	_, _, err = Repo(&drudapi.Application{AppID: "norepo"})
+	if err == nil {
+		t.Error("Expected an error for an application without a repo")
+	}
+}
+
+func TestEnsureHook(t *testing.T) {
+	f := &fakeGithub{
+		t:     t,
+		repos: map[string][]string{"drud/site": {"master"}},
+		hooks: map[int]*github.Hook{
+			7: {ID: github.Int(7), Name: github.String("web"), Config: map[string]interface{}{"url": "https://hooks.drud.io/github/old"}},
+			8: {ID: github.Int(8), Name: github.String("web"), Config: map[string]interface{}{"url": "https://ci.example.com/hook"}},
+		},
+		nextID: 10,
+	}
+	c, done := testClient(t, f)
+	defer done()
+
+	patches := 0
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		patches++
+		var app drudapi.Application
+		json.NewDecoder(r.Body).Decode(&app)
+		if app.GithubHookID != 11 {
+			t.Errorf("Expected hook id 11 to be saved, got %d", app.GithubHookID)
+		}
+		fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+	}))
+	defer api.Close()
+	r := &drudapi.Request{Host: api.URL}
+
+	app := &drudapi.Application{AppID: "site", RepoOrg: "drud", Repo: "site", Etag: "etag1"}
+	target := "https://hooks.drud.io/github/site"
+
+	hook, err := c.EnsureHook(r, app, target, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *hook.ID != 11 || app.GithubHookID != 11 || patches != 1 {
+		t.Errorf("Expected hook 11 to be created and saved, got %d %d %d", *hook.ID, app.GithubHookID, patches)
+	}
+	if f.hooks[11].Events[0] != "push" || f.hooks[11].Config["secret"] != "s3cret" {
+		t.Errorf("Hook not configured for push: %v", f.hooks[11])
+	}
+
+	// a second call updates the existing hook without saving the application again
+	hook, err = c.EnsureHook(r, app, target, "n3w")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *hook.ID != 11 || len(f.hooks) != 3 || patches != 1 {
+		t.Errorf("Expected hook 11 to be updated, got %d with %d hooks", *hook.ID, len(f.hooks))
+	}
+	if f.hooks[11].Config["secret"] != "n3w" {
+		t.Errorf("Hook secret not updated: %v", f.hooks[11].Config)
+	}
+
+	orphans, err := c.OrphanedHooks(app, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || *orphans[0].ID != 7 {
+		t.Errorf("Expected hook 7 to be orphaned, got %v", orphans)
+	}
+
+	removed, err := c.RemoveOrphanedHooks(app, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 1 || f.hooks[7] != nil || f.hooks[8] == nil {
+		t.Errorf("Expected only hook 7 to be removed, %d removed", removed)
+	}
+}
+
+func TestEnsureHookAdoptsExisting(t *testing.T) {
+	target := "https://hooks.drud.io/github/site"
+	f := &fakeGithub{
+		t:     t,
+		repos: map[string][]string{"drud/site": {"master"}},
+		hooks: map[int]*github.Hook{
+			5: {ID: github.Int(5), Name: github.String("web"), Config: map[string]interface{}{"url": target}},
+		},
+		nextID: 10,
+	}
+	c, done := testClient(t, f)
+	defer done()
+
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"_etag": "etag2", "_status": "OK"}`)
+	}))
+	defer api.Close()
+
+	// the recorded hook was deleted on github so the one already delivering to target is used
+	app := &drudapi.Application{AppID: "site", RepoOrg: "drud", Repo: "site", GithubHookID: 3, Etag: "etag1"}
+	hook, err := c.EnsureHook(&drudapi.Request{Host: api.URL}, app, target, "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *hook.ID != 5 || app.GithubHookID != 5 || len(f.hooks) != 1 {
+		t.Errorf("Expected hook 5 to be adopted, got %d", app.GithubHookID)
+	}
+}
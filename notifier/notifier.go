@@ -0,0 +1,246 @@
+// Package notifier posts DRUD build and deploy health events to the slack channel of
+// the application they happened to.
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/drud/drud-go/drudapi"
+)
+
+// Events a Notifier posts to an application's slack channel
+const (
+	EventBuildStarted    = "build_started"
+	EventBuildSucceeded  = "build_succeeded"
+	EventBuildFailed     = "build_failed"
+	EventDeployHealthy   = "deploy_healthy"
+	EventDeployUnhealthy = "deploy_unhealthy"
+)
+
+// DefaultTemplates are the message templates used for events the Notifier has no template for
+var DefaultTemplates = map[string]string{
+	EventBuildStarted:    "Build {{.Build.Build}} of {{.AppID}}/{{.Deploy}} started from {{.Build.Branch}}",
+	EventBuildSucceeded:  "Build {{.Build.Build}} of {{.AppID}}/{{.Deploy}} succeeded{{if .URL}}, see {{.URL}}{{end}}",
+	EventBuildFailed:     "Build {{.Build.Build}} of {{.AppID}}/{{.Deploy}} failed",
+	EventDeployHealthy:   "{{.AppID}}/{{.Deploy}} is healthy again at {{.URL}}",
+	EventDeployUnhealthy: "{{.AppID}}/{{.Deploy}} is unhealthy at {{.URL}}{{if .Health.Err}}: {{.Health.Err}}{{end}}",
+}
+
+// eventColors are the slack attachment colors of each event
+var eventColors = map[string]string{
+	EventBuildStarted:    "#439fe0",
+	EventBuildSucceeded:  "good",
+	EventBuildFailed:     "danger",
+	EventDeployHealthy:   "good",
+	EventDeployUnhealthy: "danger",
+}
+
+// SlackAttachment is an attachment of an incoming webhook message
+type SlackAttachment struct {
+	Fallback  string `json:"fallback"`
+	Color     string `json:"color,omitempty"`
+	Title     string `json:"title,omitempty"`
+	TitleLink string `json:"title_link,omitempty"`
+	Text      string `json:"text"`
+}
+
+// SlackMessage is the payload posted to a slack incoming webhook
+type SlackMessage struct {
+	Channel     string            `json:"channel,omitempty"`
+	Username    string            `json:"username,omitempty"`
+	IconEmoji   string            `json:"icon_emoji,omitempty"`
+	Text        string            `json:"text,omitempty"`
+	Attachments []SlackAttachment `json:"attachments,omitempty"`
+}
+
+// Notification is the data message templates are executed with
+type Notification struct {
+	Event  string
+	AppID  string
+	Deploy string
+	URL    string
+	Build  *drudapi.Build        // set for build events
+	Health *drudapi.DeployHealth // set for health events
+}
+
+// Notifier posts build and deploy health events to the slack channel of the application
+// they happened to
+type Notifier struct {
+	WebhookURL string
+	Username   string
+	IconEmoji  string
+	Templates  map[string]string // message templates by event, DefaultTemplates are used for the rest
+	Retries    int               // attempts after the first when slack is unavailable
+	RetryWait  time.Duration     // wait between attempts unless slack sends Retry-After
+	Client     *http.Client
+}
+
+// New returns a notifier that posts to an incoming webhook
+func New(webhookURL string) *Notifier {
+	return &Notifier{
+		WebhookURL: webhookURL,
+		Username:   "drud",
+		Retries:    3,
+		RetryWait:  2 * time.Second,
+	}
+}
+
+// Message renders the slack message for a notification
+func (n *Notifier) Message(channel string, note Notification) (*SlackMessage, error) {
+	text, ok := n.Templates[note.Event]
+	if !ok {
+		text, ok = DefaultTemplates[note.Event]
+	}
+	if !ok {
+		return nil, fmt.Errorf("No template for event %s", note.Event)
+	}
+
+	tmpl, err := template.New(note.Event).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid template for event %s: %s", note.Event, err)
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, note)
+	if err != nil {
+		return nil, fmt.Errorf("Could not render template for event %s: %s", note.Event, err)
+	}
+
+	if channel != "" && !strings.HasPrefix(channel, "#") && !strings.HasPrefix(channel, "@") {
+		channel = "#" + channel
+	}
+
+	return &SlackMessage{
+		Channel:   channel,
+		Username:  n.Username,
+		IconEmoji: n.IconEmoji,
+		Attachments: []SlackAttachment{{
+			Fallback:  buf.String(),
+			Color:     eventColors[note.Event],
+			Title:     note.AppID + "/" + note.Deploy,
+			TitleLink: note.URL,
+			Text:      buf.String(),
+		}},
+	}, nil
+}
+
+// Notify posts a notification to the application's slack channel. Nothing is posted for
+// applications without a SlackChannel.
+func (n *Notifier) Notify(app *drudapi.Application, note Notification) error {
+	if app.SlackChannel == "" {
+		return nil
+	}
+
+	note.AppID = app.AppID
+	msg, err := n.Message(app.SlackChannel, note)
+	if err != nil {
+		return err
+	}
+	return n.post(msg)
+}
+
+// post sends msg to the webhook, retrying while slack is unreachable, rate limiting or
+// failing with a server error
+func (n *Notifier) post(msg *SlackMessage) error {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	client := n.Client
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	for attempt := 0; ; attempt++ {
+		wait := n.RetryWait
+		resp, err := client.Post(n.WebhookURL, "application/json", bytes.NewReader(payload))
+		if err == nil {
+			body, _ := ioutil.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			switch {
+			case resp.StatusCode-200 < 100:
+				return nil
+			case resp.StatusCode == http.StatusTooManyRequests:
+				if seconds, convErr := strconv.Atoi(resp.Header.Get("Retry-After")); convErr == nil {
+					wait = time.Duration(seconds) * time.Second
+				}
+				err = fmt.Errorf("Slack rate limited: %s", resp.Status)
+			case resp.StatusCode >= 500:
+				err = fmt.Errorf("Slack unavailable: %s", resp.Status)
+			default:
+				// the message was rejected, retrying will not help
+				return fmt.Errorf("Slack rejected message: %s %s", resp.Status, strings.TrimSpace(string(body)))
+			}
+		}
+
+		if attempt >= n.Retries {
+			return err
+		}
+		time.Sleep(wait)
+	}
+}
+
+// NotifyBuild posts that b started, succeeded or failed. Cancelled builds are not posted.
+func (n *Notifier) NotifyBuild(app *drudapi.Application, b *drudapi.Build) error {
+	note := Notification{
+		Deploy: b.DeployName,
+		Build:  b,
+	}
+	if d := app.GetDeploy(b.DeployName); d != nil {
+		note.URL = d.SiteURL()
+	}
+
+	switch b.State {
+	case drudapi.BuildStateSuccess:
+		note.Event = EventBuildSucceeded
+	case drudapi.BuildStateFailed:
+		note.Event = EventBuildFailed
+	case drudapi.BuildStateCancelled, drudapi.BuildStateCancelRequested:
+		return nil
+	default:
+		note.Event = EventBuildStarted
+	}
+	return n.Notify(app, note)
+}
+
+// NotifyHealth posts each deploy whose health changed between two reports from
+// Application.CheckHealth. Deploys missing from previous are not posted.
+func (n *Notifier) NotifyHealth(app *drudapi.Application, previous []drudapi.DeployHealth, current []drudapi.DeployHealth) error {
+	was := make(map[string]bool)
+	for _, h := range previous {
+		was[h.Deploy] = h.Healthy
+	}
+
+	for i, h := range current {
+		healthy, ok := was[h.Deploy]
+		if !ok || healthy == h.Healthy {
+			continue
+		}
+
+		note := Notification{
+			Event:  EventDeployUnhealthy,
+			Deploy: h.Deploy,
+			URL:    h.URL,
+			Health: &current[i],
+		}
+		if h.Healthy {
+			note.Event = EventDeployHealthy
+		}
+
+		err := n.Notify(app, note)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
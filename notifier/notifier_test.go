@@ -0,0 +1,156 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/drud/drud-go/drudapi"
+)
+
+func expect(t *testing.T, a interface{}, b interface{}) {
+	if a != b {
+		t.Errorf("Expected %v (type %v) - Got %v (type %v)", b, reflect.TypeOf(b), a, reflect.TypeOf(a))
+	}
+}
+
+func refute(t *testing.T, a interface{}, b interface{}) {
+	if a == b {
+		t.Errorf("Did not expect %v (type %v) - Got %v (type %v)", b, reflect.TypeOf(b), a, reflect.TypeOf(a))
+	}
+}
+
+// webhookTestServer stands in for a slack incoming webhook, failing with each status in
+// failures before accepting messages
+func webhookTestServer(t *testing.T, messages *[]SlackMessage, failures ...int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expect(t, r.Header.Get("Content-Type"), "application/json")
+		if len(failures) > 0 {
+			w.WriteHeader(failures[0])
+			failures = failures[1:]
+			return
+		}
+
+		var msg SlackMessage
+		err := json.NewDecoder(r.Body).Decode(&msg)
+		if err != nil {
+			t.Error(err)
+		}
+		*messages = append(*messages, msg)
+		w.Write([]byte("ok"))
+	}))
+}
+
+func TestNotifyBuild(t *testing.T) {
+	var messages []SlackMessage
+	server := webhookTestServer(t, &messages, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	defer server.Close()
+
+	n := New(server.URL)
+	n.RetryWait = 0
+
+	app := &drudapi.Application{
+		AppID:        "myapp",
+		SlackChannel: "deploys",
+		Deploys:      []drudapi.Deploy{{Name: "production", Protocol: "https", Hostname: "myapp.example.com"}},
+	}
+	b := &drudapi.Build{Build: 12, DeployName: "production", Branch: "master", State: "building"}
+
+	err := n.NotifyBuild(app, b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(messages), 1)
+	expect(t, messages[0].Channel, "#deploys")
+	expect(t, messages[0].Attachments[0].Text, "Build 12 of myapp/production started from master")
+
+	b.State = drudapi.BuildStateSuccess
+	err = n.NotifyBuild(app, b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, messages[1].Attachments[0].Text, "Build 12 of myapp/production succeeded, see https://myapp.example.com")
+	expect(t, messages[1].Attachments[0].Color, "good")
+
+	n.Templates = map[string]string{EventBuildFailed: ":boom: {{.AppID}} {{.Build.Branch}}"}
+	b.State = drudapi.BuildStateFailed
+	err = n.NotifyBuild(app, b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, messages[2].Attachments[0].Text, ":boom: myapp master")
+
+	b.State = drudapi.BuildStateCancelled
+	err = n.NotifyBuild(app, b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	app.SlackChannel = ""
+	b.State = drudapi.BuildStateFailed
+	err = n.NotifyBuild(app, b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(messages), 3)
+}
+
+func TestNotifyGivesUp(t *testing.T) {
+	var messages []SlackMessage
+	server := webhookTestServer(t, &messages, 500, 500, 500, 404)
+	defer server.Close()
+
+	n := New(server.URL)
+	n.RetryWait = 0
+	n.Retries = 1
+
+	app := &drudapi.Application{AppID: "myapp", SlackChannel: "#deploys"}
+	err := n.NotifyBuild(app, &drudapi.Build{DeployName: "production", State: drudapi.BuildStateFailed})
+	refute(t, err, nil)
+
+	// a rejected message is not retried
+	n.Retries = 5
+	err = n.NotifyBuild(app, &drudapi.Build{DeployName: "production", State: drudapi.BuildStateFailed})
+	refute(t, err, nil)
+	expect(t, len(messages), 0)
+
+	err = n.NotifyBuild(app, &drudapi.Build{DeployName: "production", State: drudapi.BuildStateFailed})
+	expect(t, err, nil)
+	expect(t, len(messages), 1)
+}
+
+func TestNotifyHealth(t *testing.T) {
+	var messages []SlackMessage
+	server := webhookTestServer(t, &messages)
+	defer server.Close()
+
+	n := New(server.URL)
+	app := &drudapi.Application{AppID: "myapp", SlackChannel: "deploys"}
+
+	previous := []drudapi.DeployHealth{
+		{Deploy: "production", URL: "http://prod", Healthy: true},
+		{Deploy: "staging", URL: "http://staging", Healthy: false},
+	}
+	current := []drudapi.DeployHealth{
+		{Deploy: "production", URL: "http://prod", Healthy: false, Err: errors.New("503 Service Unavailable")},
+		{Deploy: "staging", URL: "http://staging", Healthy: false},
+		{Deploy: "review", URL: "http://review", Healthy: false},
+	}
+
+	err := n.NotifyHealth(app, previous, current)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(messages), 1)
+	expect(t, messages[0].Attachments[0].Text, "myapp/production is unhealthy at http://prod: 503 Service Unavailable")
+
+	err = n.NotifyHealth(app, current, previous)
+	if err != nil {
+		log.Fatal(err)
+	}
+	expect(t, len(messages), 2)
+	expect(t, messages[1].Attachments[0].Text, "myapp/production is healthy again at http://prod")
+}
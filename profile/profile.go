@@ -0,0 +1,217 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/drud/drud-go/drudapi"
+	"github.com/drud/drud-go/files"
+	"github.com/ghodss/yaml"
+	vaultAPI "github.com/hashicorp/vault/api"
+)
+
+// Credential sources a profile can use to authenticate with the drud api
+const (
+	CredentialsStore = "store" // token saved by drudapi login, the default
+	CredentialsEnv   = "env"   // DRUD_AUTH_TOKEN or DRUD_ADMIN_TOKEN
+)
+
+// Profile describes a named DRUD environment
+type Profile struct {
+	Name              string `json:"-"`
+	APIHost           string `json:"api_host"`
+	CredentialsSource string `json:"credentials,omitempty"`
+	VaultAddress      string `json:"vault_address,omitempty"`
+	Bucket            string `json:"bucket,omitempty"`
+	Region            string `json:"region,omitempty"`
+}
+
+// Config holds all profiles and the name of the one in use
+type Config struct {
+	Current  string              `json:"current,omitempty"`
+	Profiles map[string]*Profile `json:"profiles"`
+}
+
+// DefaultPath returns $DRUD_CONFIG or drud/profiles.yaml in the user's config dir
+func DefaultPath() (string, error) {
+	if path := os.Getenv("DRUD_CONFIG"); path != "" {
+		return path, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "drud", "profiles.yaml"), nil
+}
+
+// Load reads the config at path. A missing file results in an empty config.
+func Load(path string) (*Config, error) {
+	c := &Config{
+		Profiles: make(map[string]*Profile),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(data, c)
+	if err != nil {
+		return nil, fmt.Errorf("Could not parse %s: %s", path, err)
+	}
+
+	if c.Profiles == nil {
+		c.Profiles = make(map[string]*Profile)
+	}
+	for name, p := range c.Profiles {
+		if p == nil {
+			return nil, fmt.Errorf("Profile %s in %s is empty", name, path)
+		}
+		p.Name = name
+	}
+	return c, nil
+}
+
+// Save writes the config to path
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Names returns the sorted names of all profiles
+func (c *Config) Names() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Set adds or replaces the profile p
+func (c *Config) Set(p *Profile) error {
+	if p.Name == "" {
+		return errors.New("Profile must have a name")
+	}
+	c.Profiles[p.Name] = p
+	return nil
+}
+
+// Get returns a copy of the named profile with environment overrides applied
+func (c *Config) Get(name string) (*Profile, error) {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("No profile found by name %s", name)
+	}
+
+	selected := *p
+	selected.Name = name
+	selected.applyEnv()
+	return &selected, nil
+}
+
+// Selected returns the profile named by $DRUD_PROFILE, falling back to Current
+func (c *Config) Selected() (*Profile, error) {
+	name := os.Getenv("DRUD_PROFILE")
+	if name == "" {
+		name = c.Current
+	}
+	if name == "" {
+		return nil, errors.New("No profile selected. Set DRUD_PROFILE or the current profile.")
+	}
+	return c.Get(name)
+}
+
+// applyEnv overrides profile settings with any that are set in the environment
+func (p *Profile) applyEnv() {
+	overrides := map[string]*string{
+		"DRUD_API_HOST":     &p.APIHost,
+		"DRUD_CREDENTIALS":  &p.CredentialsSource,
+		"DRUD_VAULT_ADDR":   &p.VaultAddress,
+		"DRUD_FILES_BUCKET": &p.Bucket,
+		"DRUD_FILES_REGION": &p.Region,
+	}
+	for env, field := range overrides {
+		if value := os.Getenv(env); value != "" {
+			*field = value
+		}
+	}
+}
+
+// Request returns a drudapi request for the profile's host using its credentials source
+func (p *Profile) Request() (*drudapi.Request, error) {
+	if p.APIHost == "" {
+		return nil, fmt.Errorf("Profile %s has no api_host", p.Name)
+	}
+
+	switch p.CredentialsSource {
+	case "", CredentialsStore:
+		return drudapi.NewRequest(p.APIHost)
+	case CredentialsEnv:
+		creds := &drudapi.Credentials{
+			Token:      os.Getenv("DRUD_AUTH_TOKEN"),
+			AdminToken: os.Getenv("DRUD_ADMIN_TOKEN"),
+		}
+		if creds.Token == "" && creds.AdminToken == "" {
+			return nil, errors.New("DRUD_AUTH_TOKEN or DRUD_ADMIN_TOKEN must be set")
+		}
+		return &drudapi.Request{
+			Host: p.APIHost,
+			Auth: creds,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("Unknown credentials source %s", p.CredentialsSource)
+}
+
+// VaultClient returns a vault client for the profile's vault address. If token is
+// empty the client uses $VAULT_TOKEN.
+func (p *Profile) VaultClient(token string) (*vaultAPI.Client, error) {
+	if p.VaultAddress == "" {
+		return nil, fmt.Errorf("Profile %s has no vault_address", p.Name)
+	}
+
+	vaultCFG := vaultAPI.DefaultConfig()
+	vaultCFG.Address = p.VaultAddress
+
+	client, err := vaultAPI.NewClient(vaultCFG)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		client.SetToken(token)
+	}
+	return client, nil
+}
+
+// FileService returns a file service for the profile's bucket and region using the
+// aws credentials in $AWS_ACCESS_KEY_ID and $AWS_SECRET_ACCESS_KEY
+func (p *Profile) FileService() (*files.FileService, error) {
+	if p.Bucket == "" || p.Region == "" {
+		return nil, fmt.Errorf("Profile %s needs a bucket and region", p.Name)
+	}
+
+	return files.NewFileService(
+		os.Getenv("AWS_ACCESS_KEY_ID"),
+		os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		p.Region,
+		p.Bucket,
+	)
+}
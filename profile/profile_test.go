@@ -0,0 +1,116 @@
+package profile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testConfig = `
+current: staging
+profiles:
+  staging:
+    api_host: https://drudapi.staging.drud.io/v0.1
+    credentials: env
+    vault_address: https://vault.staging.drud.io:8200
+    bucket: staging-files
+    region: us-west-2
+  production:
+    api_host: https://drudapi.genesis.drud.io/v0.1
+`
+
+func writeTestConfig(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "profiles.yaml")
+	err = ioutil.WriteFile(path, []byte(testConfig), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestSelectedProfile(t *testing.T) {
+	path, cleanup := writeTestConfig(t)
+	defer cleanup()
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := c.Selected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "staging" || p.Bucket != "staging-files" || p.Region != "us-west-2" {
+		t.Errorf("Unexpected profile %+v", p)
+	}
+
+	os.Setenv("DRUD_PROFILE", "production")
+	os.Setenv("DRUD_API_HOST", "http://localhost:8080")
+	defer os.Unsetenv("DRUD_PROFILE")
+	defer os.Unsetenv("DRUD_API_HOST")
+
+	p, err = c.Selected()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Name != "production" || p.APIHost != "http://localhost:8080" {
+		t.Errorf("Unexpected profile %+v", p)
+	}
+	// overrides must not leak into the stored config
+	if c.Profiles["production"].APIHost != "https://drudapi.genesis.drud.io/v0.1" {
+		t.Errorf("Config was modified by env overrides")
+	}
+
+	_, err = c.Get("missing")
+	if err == nil {
+		t.Errorf("Expected error for missing profile")
+	}
+}
+
+func TestProfileRequest(t *testing.T) {
+	path, cleanup := writeTestConfig(t)
+	defer cleanup()
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := c.Get("staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Unsetenv("DRUD_AUTH_TOKEN")
+	os.Unsetenv("DRUD_ADMIN_TOKEN")
+	_, err = p.Request()
+	if err == nil {
+		t.Errorf("Expected error when no token is in the environment")
+	}
+
+	os.Setenv("DRUD_AUTH_TOKEN", "sometoken")
+	defer os.Unsetenv("DRUD_AUTH_TOKEN")
+
+	r, err := p.Request()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Host != p.APIHost || r.Auth.Token != "sometoken" {
+		t.Errorf("Unexpected request %+v", r)
+	}
+
+	v, err := p.VaultClient("vaulttoken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Address() != p.VaultAddress || v.Token() != "vaulttoken" {
+		t.Errorf("Unexpected vault client for %s", v.Address())
+	}
+}
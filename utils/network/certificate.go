@@ -0,0 +1,122 @@
+package network
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// CertificateInfo describes the certificate a TLS server presented
+type CertificateInfo struct {
+	Address    string
+	ServerName string
+	Subject    string
+	Issuer     string
+	SANs       []string
+	NotBefore  time.Time
+	NotAfter   time.Time
+	// Chain is the subject of each certificate the server sent, leaf first
+	Chain []string
+	// HostnameMismatch is set when the certificate is not valid for ServerName
+	HostnameMismatch bool
+	// ChainError is why the chain could not be verified against the trusted roots
+	ChainError string
+}
+
+// DaysToExpiry returns the whole days left before the certificate expires at now,
+// negative once it has expired
+func (c *CertificateInfo) DaysToExpiry(now time.Time) int {
+	return int(c.NotAfter.Sub(now).Hours() / 24)
+}
+
+// ExpiresWithin reports whether the certificate expires within d from now
+func (c *CertificateInfo) ExpiresWithin(d time.Duration) bool {
+	return time.Now().Add(d).After(c.NotAfter)
+}
+
+// Valid reports whether the certificate is trusted, current and matches ServerName
+func (c *CertificateInfo) Valid() bool {
+	now := time.Now()
+	return !c.HostnameMismatch && c.ChainError == "" && now.After(c.NotBefore) && now.Before(c.NotAfter)
+}
+
+// InspectCertificate connects to address and describes the certificate presented for
+// serverName. The connection is made without verification so invalid certificates can
+// still be described, the chain is then verified against roots or the system roots if
+// roots is nil.
+func InspectCertificate(address string, serverName string, roots *x509.CertPool, timeout time.Duration) (*CertificateInfo, error) {
+	if serverName == "" {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		serverName = host
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", address, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return nil, errors.New("No certificate presented by " + address)
+	}
+	leaf := certs[0]
+
+	info := &CertificateInfo{
+		Address:    address,
+		ServerName: serverName,
+		Subject:    leaf.Subject.String(),
+		Issuer:     leaf.Issuer.String(),
+		SANs:       append([]string{}, leaf.DNSNames...),
+		NotBefore:  leaf.NotBefore,
+		NotAfter:   leaf.NotAfter,
+	}
+	for _, ip := range leaf.IPAddresses {
+		info.SANs = append(info.SANs, ip.String())
+	}
+	for _, cert := range certs {
+		info.Chain = append(info.Chain, cert.Subject.String())
+	}
+
+	info.HostnameMismatch = leaf.VerifyHostname(serverName) != nil
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		info.ChainError = err.Error()
+	}
+	return info, nil
+}
+
+// String summarises the certificate on a single line
+func (c *CertificateInfo) String() string {
+	var problems []string
+	if c.HostnameMismatch {
+		problems = append(problems, "hostname mismatch")
+	}
+	if c.ChainError != "" {
+		problems = append(problems, c.ChainError)
+	}
+	summary := fmt.Sprintf("%s issued by %s expires in %d days", c.ServerName, c.Issuer, c.DaysToExpiry(time.Now()))
+	if len(problems) > 0 {
+		summary += " (" + strings.Join(problems, ", ") + ")"
+	}
+	return summary
+}
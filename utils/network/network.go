@@ -1,6 +1,8 @@
 package network
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"net/http"
@@ -18,6 +20,7 @@ type HTTPOptions struct {
 	TickerInterval time.Duration
 	ExpectedStatus int
 	Headers        map[string]string
+	RootCAs        *x509.CertPool // trusted roots for https, the system roots when nil
 }
 
 // NewHTTPOptions returns a new HTTPOptions struct with some sane defaults.
@@ -54,19 +57,9 @@ func EnsureHTTPStatus(o *HTTPOptions) error {
 	for {
 		select {
 		case <-queryTicker:
-			req, err := http.NewRequest("GET", o.URL, nil)
-			if o.Username != "" && o.Password != "" {
-				req.SetBasicAuth(o.Username, o.Password)
-			}
-
-			if len(o.Headers) > 0 {
-				for header, value := range o.Headers {
-					if header == "Host" {
-						req.Host = value
-						continue
-					}
-					req.Header.Add(header, value)
-				}
+			req, err := newRequest(o)
+			if err != nil {
+				return err
 			}
 			// Make the request
 			resp, err := client.Do(req)
@@ -96,3 +89,105 @@ func EnsureHTTPStatus(o *HTTPOptions) error {
 		}
 	}
 }
+
+// HTTPResult is the outcome of a single request made by CheckHTTPStatus
+type HTTPResult struct {
+	StatusCode int
+	Latency    time.Duration
+	TLSExpiry  time.Time // expiry of the leaf certificate, zero for plain http
+	TLSError   error     // why the certificate was rejected, nil when it verified
+}
+
+// CheckHTTPStatus makes a single request to o.URL and returns its result. An error
+// is returned if the request fails, the certificate does not verify against
+// o.RootCAs or the status does not match o.ExpectedStatus.
+func CheckHTTPStatus(o *HTTPOptions) (*HTTPResult, error) {
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = 60
+	}
+
+	req, err := newRequest(o)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HTTPResult{}
+	client := &http.Client{
+		Timeout: time.Second * timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				// the chain is verified in VerifyConnection instead so the expiry
+				// of a rejected certificate can still be reported
+				InsecureSkipVerify: true,
+				VerifyConnection: func(state tls.ConnectionState) error {
+					if len(state.PeerCertificates) > 0 {
+						result.TLSExpiry = state.PeerCertificates[0].NotAfter
+					}
+					result.TLSError = verifyPeer(state, req.URL.Hostname(), o.RootCAs)
+					return result.TLSError
+				},
+			},
+		},
+	}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		if result.TLSError != nil {
+			return result, fmt.Errorf("%s has an invalid certificate: %s", o.URL, result.TLSError)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	result.Latency = time.Since(start)
+
+	if resp.StatusCode != o.ExpectedStatus {
+		return result, fmt.Errorf("%s returned %d, expected %d", o.URL, resp.StatusCode, o.ExpectedStatus)
+	}
+	return result, nil
+}
+
+// verifyPeer checks the certificate chain presented in state against roots and host
+func verifyPeer(state tls.ConnectionState, host string, roots *x509.CertPool) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("no certificate presented")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	return err
+}
+
+// newRequest builds a GET request for o.URL with its auth and headers set
+func newRequest(o *HTTPOptions) (*http.Request, error) {
+	req, err := http.NewRequest("GET", o.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.Username != "" && o.Password != "" {
+		req.SetBasicAuth(o.Username, o.Password)
+	}
+
+	for header, value := range o.Headers {
+		if header == "Host" {
+			req.Host = value
+			continue
+		}
+		req.Header.Add(header, value)
+	}
+	return req, nil
+}
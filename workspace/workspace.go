@@ -0,0 +1,192 @@
+// Package workspace checks out the repositories of DRUD applications so a deploy's code
+// can be worked on locally.
+package workspace
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/drud/drud-go/drudapi"
+)
+
+// Workspace is a directory applications are checked out into, one directory per deploy
+type Workspace struct {
+	Root  string
+	Token string // github token used to fetch, never written to the checkout
+	Git   string // git binary, defaults to git on the PATH
+}
+
+// Checkout is the result of checking out a deploy
+type Checkout struct {
+	Dir    string
+	URL    string
+	Branch string
+	Commit string // the commit checked out
+	Cloned bool   // false if an existing checkout was updated
+}
+
+// CloneURL returns the url the application's repository is cloned from, without any
+// credentials. RepoDetails.CloneURL is used if set, otherwise the url is built from its
+// Host, Org and Name.
+func CloneURL(app *drudapi.Application) (string, error) {
+	d := app.RepoDetails
+	if d == nil {
+		return "", fmt.Errorf("Application %s has no repo details", app.AppID)
+	}
+
+	if d.CloneURL != "" {
+		u, err := url.Parse(d.CloneURL)
+		if err != nil {
+			return "", err
+		}
+		u.User = nil
+		return u.String(), nil
+	}
+
+	name := d.Name
+	if name == "" {
+		name = app.Name
+	}
+	if d.Host == "" || d.Org == "" || name == "" {
+		return "", fmt.Errorf("Application %s has no clone url", app.AppID)
+	}
+	return fmt.Sprintf("https://%s/%s/%s.git", d.Host, d.Org, name), nil
+}
+
+// env returns the environment git is run with. The token is passed as an http header
+// through GIT_CONFIG_* so it is neither stored in .git/config nor visible in the
+// process list.
+func (w *Workspace) env() []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if w.Token == "" {
+		return env
+	}
+
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + w.Token))
+	return append(env,
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.extraHeader",
+		"GIT_CONFIG_VALUE_0=Authorization: Basic "+auth,
+	)
+}
+
+// git runs a git command in dir and returns its trimmed output
+func (w *Workspace) git(dir string, args ...string) (string, error) {
+	bin := w.Git
+	if bin == "" {
+		bin = "git"
+	}
+
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = dir
+	cmd.Env = w.env()
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if w.Token != "" {
+			msg = strings.Replace(msg, w.Token, "****", -1)
+		}
+		return "", fmt.Errorf("git %s failed: %s: %s", args[0], err, msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// Dir is where a deploy of the application is checked out
+func (w *Workspace) Dir(app *drudapi.Application, deployName string) string {
+	return filepath.Join(w.Root, app.AppID, deployName)
+}
+
+// Checkout clones the application's repository at the deploy's branch, or if it has
+// already been cloned fetches and fast forwards it. Local commits that have diverged
+// from the remote branch are never discarded.
+func (w *Workspace) Checkout(app *drudapi.Application, deployName string) (*Checkout, error) {
+	d := app.GetDeploy(deployName)
+	if d == nil {
+		return nil, fmt.Errorf("No deploy found by name %s", deployName)
+	}
+
+	cloneURL, err := CloneURL(app)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := d.Branch
+	if branch == "" && app.RepoDetails.Branch != "" {
+		branch = app.RepoDetails.Branch
+	}
+	if branch == "" {
+		return nil, fmt.Errorf("Deploy %s has no branch", deployName)
+	}
+
+	c := &Checkout{
+		Dir:    w.Dir(app, deployName),
+		URL:    cloneURL,
+		Branch: branch,
+	}
+
+	_, err = os.Stat(filepath.Join(c.Dir, ".git"))
+	if os.IsNotExist(err) {
+		err = os.MkdirAll(filepath.Dir(c.Dir), 0755)
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.git(filepath.Dir(c.Dir), "clone", "--branch", branch, cloneURL, c.Dir)
+		if err != nil {
+			return nil, err
+		}
+		c.Cloned = true
+	} else if err != nil {
+		return nil, err
+	} else {
+		err = w.update(c)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.Commit, err = w.git(c.Dir, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// update fetches an existing checkout and fast forwards it to the remote branch
+func (w *Workspace) update(c *Checkout) error {
+	origin, err := w.git(c.Dir, "remote", "get-url", "origin")
+	if err != nil {
+		return err
+	}
+	if origin != c.URL {
+		return fmt.Errorf("%s is a checkout of %s, not %s", c.Dir, origin, c.URL)
+	}
+
+	_, err = w.git(c.Dir, "fetch", "origin", "+refs/heads/"+c.Branch+":refs/remotes/origin/"+c.Branch)
+	if err != nil {
+		return err
+	}
+
+	current, err := w.git(c.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if err != nil {
+		return err
+	}
+	if current != c.Branch {
+		_, err = w.git(c.Dir, "checkout", c.Branch)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = w.git(c.Dir, "merge", "--ff-only", "origin/"+c.Branch)
+	return err
+}
@@ -0,0 +1,150 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/drud/drud-go/drudapi"
+)
+
+// run runs a git command for test setup
+func run(t *testing.T, dir string, args ...string) string {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=test", "GIT_AUTHOR_EMAIL=test@drud.com",
+		"GIT_COMMITTER_NAME=test", "GIT_COMMITTER_EMAIL=test@drud.com",
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %s: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commit adds a commit changing name on branch of the repo at dir and returns its sha
+func commit(t *testing.T, dir string, branch string, name string) string {
+	run(t, dir, "checkout", "-q", "-B", branch)
+	err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, dir, "add", name)
+	run(t, dir, "commit", "-q", "-m", name)
+	return run(t, dir, "rev-parse", "HEAD")
+}
+
+func TestCheckout(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	tmp, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	upstream := filepath.Join(tmp, "upstream")
+	os.Mkdir(upstream, 0755)
+	run(t, upstream, "init", "-q")
+	commit(t, upstream, "master", "README")
+	first := commit(t, upstream, "develop", "index.php")
+
+	// wrap git to record the environment it is run with
+	envLog := filepath.Join(tmp, "env.log")
+	wrapper := filepath.Join(tmp, "git.sh")
+	err = ioutil.WriteFile(wrapper, []byte("#!/bin/sh\necho \"$GIT_CONFIG_VALUE_0 $@\" >> "+envLog+"\nexec git \"$@\"\n"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &drudapi.Application{
+		AppID:   "site",
+		Deploys: []drudapi.Deploy{{Name: "staging", Branch: "develop"}},
+	}
+	app.RepoDetails = &drudapi.RepoDetails{CloneURL: "file://" + upstream}
+
+	w := &Workspace{
+		Root:  filepath.Join(tmp, "workspace"),
+		Token: "s3cret-token",
+		Git:   wrapper,
+	}
+
+	c, err := w.Checkout(app, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Cloned || c.Commit != first || c.Dir != filepath.Join(w.Root, "site", "staging") {
+		t.Errorf("Unexpected checkout %+v", c)
+	}
+
+	second := commit(t, upstream, "develop", "style.css")
+	c, err = w.Checkout(app, "staging")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Cloned || c.Commit != second {
+		t.Errorf("Expected checkout to be updated to %s, got %+v", second, c)
+	}
+
+	config, err := ioutil.ReadFile(filepath.Join(c.Dir, ".git", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(config), "s3cret-token") {
+		t.Errorf("Token leaked into .git/config:\n%s", config)
+	}
+
+	log, err := ioutil.ReadFile(envLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(log)), "\n") {
+		if !strings.HasPrefix(line, "Authorization: Basic ") {
+			t.Errorf("Expected token header for every git command, got %q", line)
+		}
+		if strings.Contains(line, "s3cret-token") {
+			t.Errorf("Token passed as an argument: %q", line)
+		}
+	}
+
+	// a diverged checkout is not overwritten
+	commit(t, c.Dir, "develop", "local.txt")
+	commit(t, upstream, "develop", "remote.txt")
+	_, err = w.Checkout(app, "staging")
+	if err == nil {
+		t.Error("Expected diverged checkout to fail")
+	}
+
+	app.Deploys = append(app.Deploys, drudapi.Deploy{Name: "production", Branch: "missing"})
+	_, err = w.Checkout(app, "production")
+	if err == nil {
+		t.Error("Expected missing branch to fail")
+	}
+}
+
+func TestCloneURL(t *testing.T) {
+	app := &drudapi.Application{AppID: "site", Name: "site"}
+	_, err := CloneURL(app)
+	if err == nil {
+		t.Error("Expected an error without repo details")
+	}
+
+	app.RepoDetails = &drudapi.RepoDetails{Host: "github.com", Org: "drud"}
+
+	u, _ := CloneURL(app)
+	if u != "https://github.com/drud/site.git" {
+		t.Errorf("Unexpected clone url %s", u)
+	}
+
+	app.RepoDetails.CloneURL = "https://token@github.com/drud/other.git"
+	u, _ = CloneURL(app)
+	if u != "https://github.com/drud/other.git" {
+		t.Errorf("Credentials not stripped from %s", u)
+	}
+}